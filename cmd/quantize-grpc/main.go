@@ -0,0 +1,146 @@
+// Command quantize-grpc serves palette generation over gRPC, for deployments that want quantization behind a
+// service boundary
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"net"
+
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/ericpauley/go-quantize/cmd/quantize-grpc/quantizepb"
+	"github.com/ericpauley/go-quantize/quantize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultMaxPixels bounds decoded images to about 256MB of histogram
+const defaultMaxPixels = 16 << 20
+
+type server struct {
+	quantizepb.UnimplementedQuantizerServer
+	// The largest image, in pixels, that requests may carry. Zero disables the limit
+	maxPixels int
+}
+
+// quantizer returns the quantizer and empty palette described by the request options
+func quantizer(o *quantizepb.Options) (quantize.MedianCutQuantizer, color.Palette) {
+	q := quantize.MedianCutQuantizer{
+		Aggregation:    quantize.Mode,
+		AddTransparent: o.GetAddTransparent(),
+	}
+	if o.GetAggregation() == quantizepb.Aggregation_AGGREGATION_MEAN {
+		q.Aggregation = quantize.Mean
+	}
+	numColors := int(o.GetNumColors())
+	if numColors <= 0 || numColors > 256 {
+		numColors = 256
+	}
+	return q, make(color.Palette, 0, numColors)
+}
+
+// decode decodes a requested image, checking its dimensions against maxPixels before any pixels are allocated
+func (s server) decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "couldn't decode image: %v", err)
+	}
+	if s.maxPixels > 0 && int64(config.Width)*int64(config.Height) > int64(s.maxPixels) {
+		return nil, status.Errorf(codes.InvalidArgument, "image of %dx%d pixels exceeds the limit of %d pixels",
+			config.Width, config.Height, s.maxPixels)
+	}
+	m, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "couldn't decode image: %v", err)
+	}
+	return m, nil
+}
+
+func colors(p color.Palette) []*quantizepb.Color {
+	out := make([]*quantizepb.Color, len(p))
+	for i, c := range p {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		out[i] = &quantizepb.Color{R: uint32(n.R), G: uint32(n.G), B: uint32(n.B), A: uint32(n.A)}
+	}
+	return out
+}
+
+func (s server) Palette(ctx context.Context, req *quantizepb.PaletteRequest) (*quantizepb.PaletteResponse, error) {
+	m, err := s.decode(req.GetImage())
+	if err != nil {
+		return nil, err
+	}
+	q, p := quantizer(req.GetOptions())
+	return &quantizepb.PaletteResponse{Palette: colors(q.Quantize(p, m))}, nil
+}
+
+func (s server) Quantize(ctx context.Context, req *quantizepb.QuantizeRequest) (*quantizepb.QuantizeResponse, error) {
+	m, err := s.decode(req.GetImage())
+	if err != nil {
+		return nil, err
+	}
+	q, p := quantizer(req.GetOptions())
+	p = q.Quantize(p, m)
+	var d draw.Drawer = draw.Src
+	if req.GetDither() {
+		d = draw.FloydSteinberg
+	}
+	paletted := image.NewPaletted(m.Bounds(), p)
+	d.Draw(paletted, paletted.Bounds(), m, m.Bounds().Min)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, paletted); err != nil {
+		return nil, status.Errorf(codes.Internal, "couldn't encode image: %v", err)
+	}
+	return &quantizepb.QuantizeResponse{Png: buf.Bytes(), Palette: colors(p)}, nil
+}
+
+func (s server) PaletteStream(stream quantizepb.Quantizer_PaletteStreamServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no frames received")
+	}
+	if err != nil {
+		return err
+	}
+	q, p := quantizer(first.GetOptions())
+	src := quantize.FrameSourceFunc(func() (image.Image, error) {
+		frame := first
+		if frame == nil {
+			if frame, err = stream.Recv(); err != nil {
+				return nil, err
+			}
+		}
+		first = nil
+		return s.decode(frame.GetImage())
+	})
+	p, err = q.QuantizeStream(p, src)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(&quantizepb.PaletteResponse{Palette: colors(p)})
+}
+
+func main() {
+	addr := flag.String("addr", ":50051", "address to listen on")
+	maxPixels := flag.Int("max-pixels", defaultMaxPixels, "largest image to accept, in pixels, or 0 for no limit")
+	flag.Parse()
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := grpc.NewServer()
+	quantizepb.RegisterQuantizerServer(s, server{maxPixels: *maxPixels})
+	log.Printf("Serving on %s", lis.Addr())
+	log.Fatal(s.Serve(lis))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"testing"
+
+	"github.com/ericpauley/go-quantize/cmd/quantize-grpc/quantizepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testClient(t *testing.T, srv server) quantizepb.QuantizerClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	quantizepb.RegisterQuantizerServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return quantizepb.NewQuantizerClient(conn)
+}
+
+func testPNG(t *testing.T, colors ...color.Color) []byte {
+	m := image.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	for x, c := range colors {
+		m.Set(x, 0, c)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPalette(t *testing.T) {
+	c := testClient(t, server{maxPixels: defaultMaxPixels})
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	resp, err := c.Palette(context.Background(), &quantizepb.PaletteRequest{
+		Image:   testPNG(t, red, blue),
+		Options: &quantizepb.Options{NumColors: 16},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetPalette()) != 2 {
+		t.Fatalf("Expected 2 colors, got %d", len(resp.GetPalette()))
+	}
+
+	if _, err := c.Palette(context.Background(), &quantizepb.PaletteRequest{Image: []byte("not an image")}); err == nil {
+		t.Fatal("Invalid image was accepted")
+	}
+}
+
+func TestMaxPixels(t *testing.T) {
+	c := testClient(t, server{maxPixels: 4})
+	red := color.RGBA{255, 0, 0, 255}
+	if _, err := c.Palette(context.Background(), &quantizepb.PaletteRequest{Image: testPNG(t, red, red, red, red)}); err != nil {
+		t.Fatalf("Expected an image at the limit to be accepted, got %v", err)
+	}
+	_, err := c.Palette(context.Background(), &quantizepb.PaletteRequest{Image: testPNG(t, red, red, red, red, red)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected an image over the limit to be rejected as an invalid argument, got %v", err)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	c := testClient(t, server{maxPixels: defaultMaxPixels})
+	resp, err := c.Quantize(context.Background(), &quantizepb.QuantizeRequest{
+		Image:  testPNG(t, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}),
+		Dither: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(bytes.NewReader(resp.GetPng()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.(*image.Paletted); !ok {
+		t.Fatal("Result is not an indexed image")
+	}
+}
+
+func TestPaletteStream(t *testing.T) {
+	c := testClient(t, server{maxPixels: defaultMaxPixels})
+	stream, err := c.PaletteStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := [][]byte{
+		testPNG(t, color.RGBA{255, 0, 0, 255}),
+		testPNG(t, color.RGBA{0, 255, 0, 255}),
+		testPNG(t, color.RGBA{0, 0, 255, 255}),
+	}
+	for i, f := range frames {
+		frame := &quantizepb.Frame{Image: f}
+		if i == 0 {
+			frame.Options = &quantizepb.Options{NumColors: 8}
+		}
+		if err := stream.Send(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetPalette()) != 3 {
+		t.Fatalf("Expected 3 colors, got %d", len(resp.GetPalette()))
+	}
+}
@@ -0,0 +1,4 @@
+// Package quantizepb contains the protocol buffer and gRPC definitions for the quantization service
+package quantizepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative quantize.proto
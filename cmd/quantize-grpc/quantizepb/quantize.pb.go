@@ -0,0 +1,574 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: quantize.proto
+
+package quantizepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Aggregation specifies how a bucket of colors is reduced to a palette entry
+type Aggregation int32
+
+const (
+	Aggregation_AGGREGATION_MODE Aggregation = 0
+	Aggregation_AGGREGATION_MEAN Aggregation = 1
+)
+
+// Enum value maps for Aggregation.
+var (
+	Aggregation_name = map[int32]string{
+		0: "AGGREGATION_MODE",
+		1: "AGGREGATION_MEAN",
+	}
+	Aggregation_value = map[string]int32{
+		"AGGREGATION_MODE": 0,
+		"AGGREGATION_MEAN": 1,
+	}
+)
+
+func (x Aggregation) Enum() *Aggregation {
+	p := new(Aggregation)
+	*p = x
+	return p
+}
+
+func (x Aggregation) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Aggregation) Descriptor() protoreflect.EnumDescriptor {
+	return file_quantize_proto_enumTypes[0].Descriptor()
+}
+
+func (Aggregation) Type() protoreflect.EnumType {
+	return &file_quantize_proto_enumTypes[0]
+}
+
+func (x Aggregation) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Aggregation.Descriptor instead.
+func (Aggregation) EnumDescriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{0}
+}
+
+// Options configures palette generation
+type Options struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The number of colors to generate, including any transparent entry. Defaults to 256
+	NumColors   uint32      `protobuf:"varint,1,opt,name=num_colors,json=numColors,proto3" json:"num_colors,omitempty"`
+	Aggregation Aggregation `protobuf:"varint,2,opt,name=aggregation,proto3,enum=quantize.v1.Aggregation" json:"aggregation,omitempty"`
+	// Whether to add a fully transparent entry
+	AddTransparent bool `protobuf:"varint,3,opt,name=add_transparent,json=addTransparent,proto3" json:"add_transparent,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Options) Reset() {
+	*x = Options{}
+	mi := &file_quantize_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Options) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Options) ProtoMessage() {}
+
+func (x *Options) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Options.ProtoReflect.Descriptor instead.
+func (*Options) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Options) GetNumColors() uint32 {
+	if x != nil {
+		return x.NumColors
+	}
+	return 0
+}
+
+func (x *Options) GetAggregation() Aggregation {
+	if x != nil {
+		return x.Aggregation
+	}
+	return Aggregation_AGGREGATION_MODE
+}
+
+func (x *Options) GetAddTransparent() bool {
+	if x != nil {
+		return x.AddTransparent
+	}
+	return false
+}
+
+// Color is a palette entry with straight 8-bit channels
+type Color struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	R             uint32                 `protobuf:"varint,1,opt,name=r,proto3" json:"r,omitempty"`
+	G             uint32                 `protobuf:"varint,2,opt,name=g,proto3" json:"g,omitempty"`
+	B             uint32                 `protobuf:"varint,3,opt,name=b,proto3" json:"b,omitempty"`
+	A             uint32                 `protobuf:"varint,4,opt,name=a,proto3" json:"a,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Color) Reset() {
+	*x = Color{}
+	mi := &file_quantize_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Color) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Color) ProtoMessage() {}
+
+func (x *Color) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Color.ProtoReflect.Descriptor instead.
+func (*Color) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Color) GetR() uint32 {
+	if x != nil {
+		return x.R
+	}
+	return 0
+}
+
+func (x *Color) GetG() uint32 {
+	if x != nil {
+		return x.G
+	}
+	return 0
+}
+
+func (x *Color) GetB() uint32 {
+	if x != nil {
+		return x.B
+	}
+	return 0
+}
+
+func (x *Color) GetA() uint32 {
+	if x != nil {
+		return x.A
+	}
+	return 0
+}
+
+type PaletteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// An image encoded as PNG, GIF or JPEG
+	Image         []byte   `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Options       *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaletteRequest) Reset() {
+	*x = PaletteRequest{}
+	mi := &file_quantize_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaletteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaletteRequest) ProtoMessage() {}
+
+func (x *PaletteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaletteRequest.ProtoReflect.Descriptor instead.
+func (*PaletteRequest) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PaletteRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *PaletteRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type PaletteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Palette       []*Color               `protobuf:"bytes,1,rep,name=palette,proto3" json:"palette,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PaletteResponse) Reset() {
+	*x = PaletteResponse{}
+	mi := &file_quantize_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PaletteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaletteResponse) ProtoMessage() {}
+
+func (x *PaletteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaletteResponse.ProtoReflect.Descriptor instead.
+func (*PaletteResponse) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PaletteResponse) GetPalette() []*Color {
+	if x != nil {
+		return x.Palette
+	}
+	return nil
+}
+
+type QuantizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// An image encoded as PNG, GIF or JPEG
+	Image   []byte   `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Options *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	// Whether to apply Floyd-Steinberg dithering when remapping
+	Dither        bool `protobuf:"varint,3,opt,name=dither,proto3" json:"dither,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuantizeRequest) Reset() {
+	*x = QuantizeRequest{}
+	mi := &file_quantize_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuantizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuantizeRequest) ProtoMessage() {}
+
+func (x *QuantizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuantizeRequest.ProtoReflect.Descriptor instead.
+func (*QuantizeRequest) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *QuantizeRequest) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *QuantizeRequest) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *QuantizeRequest) GetDither() bool {
+	if x != nil {
+		return x.Dither
+	}
+	return false
+}
+
+type QuantizeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The remapped image encoded as an indexed PNG
+	Png           []byte   `protobuf:"bytes,1,opt,name=png,proto3" json:"png,omitempty"`
+	Palette       []*Color `protobuf:"bytes,2,rep,name=palette,proto3" json:"palette,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuantizeResponse) Reset() {
+	*x = QuantizeResponse{}
+	mi := &file_quantize_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuantizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuantizeResponse) ProtoMessage() {}
+
+func (x *QuantizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuantizeResponse.ProtoReflect.Descriptor instead.
+func (*QuantizeResponse) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *QuantizeResponse) GetPng() []byte {
+	if x != nil {
+		return x.Png
+	}
+	return nil
+}
+
+func (x *QuantizeResponse) GetPalette() []*Color {
+	if x != nil {
+		return x.Palette
+	}
+	return nil
+}
+
+type Frame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// A frame encoded as PNG, GIF or JPEG
+	Image []byte `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	// Options for the whole stream, read from the first frame
+	Options       *Options `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Frame) Reset() {
+	*x = Frame{}
+	mi := &file_quantize_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Frame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Frame) ProtoMessage() {}
+
+func (x *Frame) ProtoReflect() protoreflect.Message {
+	mi := &file_quantize_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Frame.ProtoReflect.Descriptor instead.
+func (*Frame) Descriptor() ([]byte, []int) {
+	return file_quantize_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Frame) GetImage() []byte {
+	if x != nil {
+		return x.Image
+	}
+	return nil
+}
+
+func (x *Frame) GetOptions() *Options {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+var File_quantize_proto protoreflect.FileDescriptor
+
+const file_quantize_proto_rawDesc = "" +
+	"\n" +
+	"\x0equantize.proto\x12\vquantize.v1\"\x8d\x01\n" +
+	"\aOptions\x12\x1d\n" +
+	"\n" +
+	"num_colors\x18\x01 \x01(\rR\tnumColors\x12:\n" +
+	"\vaggregation\x18\x02 \x01(\x0e2\x18.quantize.v1.AggregationR\vaggregation\x12'\n" +
+	"\x0fadd_transparent\x18\x03 \x01(\bR\x0eaddTransparent\"?\n" +
+	"\x05Color\x12\f\n" +
+	"\x01r\x18\x01 \x01(\rR\x01r\x12\f\n" +
+	"\x01g\x18\x02 \x01(\rR\x01g\x12\f\n" +
+	"\x01b\x18\x03 \x01(\rR\x01b\x12\f\n" +
+	"\x01a\x18\x04 \x01(\rR\x01a\"V\n" +
+	"\x0ePaletteRequest\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\fR\x05image\x12.\n" +
+	"\aoptions\x18\x02 \x01(\v2\x14.quantize.v1.OptionsR\aoptions\"?\n" +
+	"\x0fPaletteResponse\x12,\n" +
+	"\apalette\x18\x01 \x03(\v2\x12.quantize.v1.ColorR\apalette\"o\n" +
+	"\x0fQuantizeRequest\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\fR\x05image\x12.\n" +
+	"\aoptions\x18\x02 \x01(\v2\x14.quantize.v1.OptionsR\aoptions\x12\x16\n" +
+	"\x06dither\x18\x03 \x01(\bR\x06dither\"R\n" +
+	"\x10QuantizeResponse\x12\x10\n" +
+	"\x03png\x18\x01 \x01(\fR\x03png\x12,\n" +
+	"\apalette\x18\x02 \x03(\v2\x12.quantize.v1.ColorR\apalette\"M\n" +
+	"\x05Frame\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\fR\x05image\x12.\n" +
+	"\aoptions\x18\x02 \x01(\v2\x14.quantize.v1.OptionsR\aoptions*9\n" +
+	"\vAggregation\x12\x14\n" +
+	"\x10AGGREGATION_MODE\x10\x00\x12\x14\n" +
+	"\x10AGGREGATION_MEAN\x10\x012\xdf\x01\n" +
+	"\tQuantizer\x12D\n" +
+	"\aPalette\x12\x1b.quantize.v1.PaletteRequest\x1a\x1c.quantize.v1.PaletteResponse\x12G\n" +
+	"\bQuantize\x12\x1c.quantize.v1.QuantizeRequest\x1a\x1d.quantize.v1.QuantizeResponse\x12C\n" +
+	"\rPaletteStream\x12\x12.quantize.v1.Frame\x1a\x1c.quantize.v1.PaletteResponse(\x01B@Z>github.com/ericpauley/go-quantize/cmd/quantize-grpc/quantizepbb\x06proto3"
+
+var (
+	file_quantize_proto_rawDescOnce sync.Once
+	file_quantize_proto_rawDescData []byte
+)
+
+func file_quantize_proto_rawDescGZIP() []byte {
+	file_quantize_proto_rawDescOnce.Do(func() {
+		file_quantize_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_quantize_proto_rawDesc), len(file_quantize_proto_rawDesc)))
+	})
+	return file_quantize_proto_rawDescData
+}
+
+var file_quantize_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_quantize_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_quantize_proto_goTypes = []any{
+	(Aggregation)(0),         // 0: quantize.v1.Aggregation
+	(*Options)(nil),          // 1: quantize.v1.Options
+	(*Color)(nil),            // 2: quantize.v1.Color
+	(*PaletteRequest)(nil),   // 3: quantize.v1.PaletteRequest
+	(*PaletteResponse)(nil),  // 4: quantize.v1.PaletteResponse
+	(*QuantizeRequest)(nil),  // 5: quantize.v1.QuantizeRequest
+	(*QuantizeResponse)(nil), // 6: quantize.v1.QuantizeResponse
+	(*Frame)(nil),            // 7: quantize.v1.Frame
+}
+var file_quantize_proto_depIdxs = []int32{
+	0, // 0: quantize.v1.Options.aggregation:type_name -> quantize.v1.Aggregation
+	1, // 1: quantize.v1.PaletteRequest.options:type_name -> quantize.v1.Options
+	2, // 2: quantize.v1.PaletteResponse.palette:type_name -> quantize.v1.Color
+	1, // 3: quantize.v1.QuantizeRequest.options:type_name -> quantize.v1.Options
+	2, // 4: quantize.v1.QuantizeResponse.palette:type_name -> quantize.v1.Color
+	1, // 5: quantize.v1.Frame.options:type_name -> quantize.v1.Options
+	3, // 6: quantize.v1.Quantizer.Palette:input_type -> quantize.v1.PaletteRequest
+	5, // 7: quantize.v1.Quantizer.Quantize:input_type -> quantize.v1.QuantizeRequest
+	7, // 8: quantize.v1.Quantizer.PaletteStream:input_type -> quantize.v1.Frame
+	4, // 9: quantize.v1.Quantizer.Palette:output_type -> quantize.v1.PaletteResponse
+	6, // 10: quantize.v1.Quantizer.Quantize:output_type -> quantize.v1.QuantizeResponse
+	4, // 11: quantize.v1.Quantizer.PaletteStream:output_type -> quantize.v1.PaletteResponse
+	9, // [9:12] is the sub-list for method output_type
+	6, // [6:9] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_quantize_proto_init() }
+func file_quantize_proto_init() {
+	if File_quantize_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_quantize_proto_rawDesc), len(file_quantize_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_quantize_proto_goTypes,
+		DependencyIndexes: file_quantize_proto_depIdxs,
+		EnumInfos:         file_quantize_proto_enumTypes,
+		MessageInfos:      file_quantize_proto_msgTypes,
+	}.Build()
+	File_quantize_proto = out.File
+	file_quantize_proto_goTypes = nil
+	file_quantize_proto_depIdxs = nil
+}
@@ -0,0 +1,201 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: quantize.proto
+
+package quantizepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Quantizer_Palette_FullMethodName       = "/quantize.v1.Quantizer/Palette"
+	Quantizer_Quantize_FullMethodName      = "/quantize.v1.Quantizer/Quantize"
+	Quantizer_PaletteStream_FullMethodName = "/quantize.v1.Quantizer/PaletteStream"
+)
+
+// QuantizerClient is the client API for Quantizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Quantizer builds palettes for images using median cut quantization
+type QuantizerClient interface {
+	// Palette builds a palette for a single encoded image
+	Palette(ctx context.Context, in *PaletteRequest, opts ...grpc.CallOption) (*PaletteResponse, error)
+	// Quantize builds a palette for an encoded image and returns the image remapped onto it as an indexed PNG
+	Quantize(ctx context.Context, in *QuantizeRequest, opts ...grpc.CallOption) (*QuantizeResponse, error)
+	// PaletteStream builds a single palette shared by a stream of encoded frames
+	PaletteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Frame, PaletteResponse], error)
+}
+
+type quantizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQuantizerClient(cc grpc.ClientConnInterface) QuantizerClient {
+	return &quantizerClient{cc}
+}
+
+func (c *quantizerClient) Palette(ctx context.Context, in *PaletteRequest, opts ...grpc.CallOption) (*PaletteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PaletteResponse)
+	err := c.cc.Invoke(ctx, Quantizer_Palette_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quantizerClient) Quantize(ctx context.Context, in *QuantizeRequest, opts ...grpc.CallOption) (*QuantizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuantizeResponse)
+	err := c.cc.Invoke(ctx, Quantizer_Quantize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quantizerClient) PaletteStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Frame, PaletteResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Quantizer_ServiceDesc.Streams[0], Quantizer_PaletteStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Frame, PaletteResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Quantizer_PaletteStreamClient = grpc.ClientStreamingClient[Frame, PaletteResponse]
+
+// QuantizerServer is the server API for Quantizer service.
+// All implementations must embed UnimplementedQuantizerServer
+// for forward compatibility.
+//
+// Quantizer builds palettes for images using median cut quantization
+type QuantizerServer interface {
+	// Palette builds a palette for a single encoded image
+	Palette(context.Context, *PaletteRequest) (*PaletteResponse, error)
+	// Quantize builds a palette for an encoded image and returns the image remapped onto it as an indexed PNG
+	Quantize(context.Context, *QuantizeRequest) (*QuantizeResponse, error)
+	// PaletteStream builds a single palette shared by a stream of encoded frames
+	PaletteStream(grpc.ClientStreamingServer[Frame, PaletteResponse]) error
+	mustEmbedUnimplementedQuantizerServer()
+}
+
+// UnimplementedQuantizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedQuantizerServer struct{}
+
+func (UnimplementedQuantizerServer) Palette(context.Context, *PaletteRequest) (*PaletteResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Palette not implemented")
+}
+func (UnimplementedQuantizerServer) Quantize(context.Context, *QuantizeRequest) (*QuantizeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Quantize not implemented")
+}
+func (UnimplementedQuantizerServer) PaletteStream(grpc.ClientStreamingServer[Frame, PaletteResponse]) error {
+	return status.Error(codes.Unimplemented, "method PaletteStream not implemented")
+}
+func (UnimplementedQuantizerServer) mustEmbedUnimplementedQuantizerServer() {}
+func (UnimplementedQuantizerServer) testEmbeddedByValue()                   {}
+
+// UnsafeQuantizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QuantizerServer will
+// result in compilation errors.
+type UnsafeQuantizerServer interface {
+	mustEmbedUnimplementedQuantizerServer()
+}
+
+func RegisterQuantizerServer(s grpc.ServiceRegistrar, srv QuantizerServer) {
+	// If the following call panics, it indicates UnimplementedQuantizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Quantizer_ServiceDesc, srv)
+}
+
+func _Quantizer_Palette_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PaletteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuantizerServer).Palette(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Quantizer_Palette_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuantizerServer).Palette(ctx, req.(*PaletteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Quantizer_Quantize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuantizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuantizerServer).Quantize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Quantizer_Quantize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuantizerServer).Quantize(ctx, req.(*QuantizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Quantizer_PaletteStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(QuantizerServer).PaletteStream(&grpc.GenericServerStream[Frame, PaletteResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Quantizer_PaletteStreamServer = grpc.ClientStreamingServer[Frame, PaletteResponse]
+
+// Quantizer_ServiceDesc is the grpc.ServiceDesc for Quantizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Quantizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "quantize.v1.Quantizer",
+	HandlerType: (*QuantizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Palette",
+			Handler:    _Quantizer_Palette_Handler,
+		},
+		{
+			MethodName: "Quantize",
+			Handler:    _Quantizer_Quantize_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PaletteStream",
+			Handler:       _Quantizer_PaletteStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "quantize.proto",
+}
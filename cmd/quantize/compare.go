@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/ericpauley/go-quantize/quantize"
+)
+
+// decodeFile reads an image in any registered format
+func decodeFile(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, _, err := image.Decode(f)
+	return m, err
+}
+
+func compare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	out := fs.String("o", "heat.png", "output PNG `file`")
+	scale := fs.Float64("scale", 0, "RGB distance shown as white, or 0 for the largest error")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: quantize compare [-o file] [-scale n] original quantized")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	orig, err := decodeFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	quantized, err := decodeFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if orig.Bounds() != quantized.Bounds() {
+		return fmt.Errorf("bounds differ: %v and %v", orig.Bounds(), quantized.Bounds())
+	}
+
+	// Report the error per channel, so PSNR matches the usual definition
+	var sum float64
+	b := orig.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, _ := orig.At(x, y).RGBA()
+			r2, g2, b2, _ := quantized.At(x, y).RGBA()
+			dr, dg, db := float64(r1>>8)-float64(r2>>8), float64(g1>>8)-float64(g2>>8), float64(b1>>8)-float64(b2>>8)
+			sum += dr*dr + dg*dg + db*db
+		}
+	}
+	mse := sum / float64(b.Dx()*b.Dy()) / 3
+	fmt.Printf("mean squared error: %.3f\n", mse)
+	if mse > 0 {
+		fmt.Printf("PSNR: %.2f dB\n", 10*math.Log10(255*255/mse))
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, quantize.HeatMap(orig, quantized, *scale)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+)
+
+const (
+	// The height of the palette strip below each frame
+	stripHeight = 8
+	// The height of the bar showing each frame's encoded size
+	barHeight = 6
+	// The gap between cells of the sheet
+	gap = 4
+)
+
+var (
+	background = color.RGBA{48, 48, 48, 255}
+	frameEdge  = color.RGBA{255, 0, 255, 255}
+	sizeBar    = color.RGBA{255, 160, 0, 255}
+)
+
+// frameSize returns the number of bytes frame i of g occupies when encoded alone, including any local color table
+func frameSize(g *gif.GIF, i int) (int, error) {
+	single := &gif.GIF{Image: g.Image[i : i+1], Delay: []int{0}, Config: g.Config}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, single); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
+
+// outline draws a one pixel border just inside r
+func outline(m draw.Image, r image.Rectangle, c color.Color) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		m.Set(x, r.Min.Y, c)
+		m.Set(x, r.Max.Y-1, c)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		m.Set(r.Min.X, y, c)
+		m.Set(r.Max.X-1, y, c)
+	}
+}
+
+// renderSheet lays out the composited frames of g in a grid. Below each frame are its palette and a bar scaled to
+// its encoded size, and the region the frame actually covers is outlined.
+func renderSheet(g *gif.GIF) (*image.RGBA, []int, error) {
+	if len(g.Image) == 0 {
+		return nil, nil, errors.New("no frames")
+	}
+	w, h := g.Config.Width, g.Config.Height
+	if w == 0 || h == 0 {
+		b := g.Image[0].Bounds()
+		w, h = b.Max.X, b.Max.Y
+	}
+	sizes := make([]int, len(g.Image))
+	largest := 1
+	for i := range g.Image {
+		size, err := frameSize(g, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		sizes[i] = size
+		if size > largest {
+			largest = size
+		}
+	}
+
+	cols := int(math.Ceil(math.Sqrt(float64(len(g.Image)))))
+	rows := (len(g.Image) + cols - 1) / cols
+	cellW, cellH := w+gap, h+stripHeight+barHeight+gap
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*cellW+gap, rows*cellH+gap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, frame := range g.Image {
+		var restore *image.RGBA
+		if len(g.Disposal) > i && g.Disposal[i] == gif.DisposalPrevious {
+			restore = image.NewRGBA(canvas.Bounds())
+			copy(restore.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		origin := image.Pt(gap+i%cols*cellW, gap+i/cols*cellH)
+		draw.Draw(sheet, canvas.Bounds().Add(origin), canvas, image.Point{}, draw.Src)
+		outline(sheet, frame.Bounds().Add(origin).Intersect(canvas.Bounds().Add(origin)), frameEdge)
+
+		swatch := w / len(frame.Palette)
+		if swatch < 1 {
+			swatch = 1
+		}
+		for j, c := range frame.Palette {
+			r := image.Rect(j*swatch, h, (j+1)*swatch, h+stripHeight).Add(origin)
+			draw.Draw(sheet, r.Intersect(sheet.Bounds()), image.NewUniform(c), image.Point{}, draw.Src)
+		}
+		bar := image.Rect(0, h+stripHeight+1, w*sizes[i]/largest, h+stripHeight+barHeight).Add(origin)
+		draw.Draw(sheet, bar, image.NewUniform(sizeBar), image.Point{}, draw.Src)
+
+		switch {
+		case restore != nil:
+			canvas = restore
+		case len(g.Disposal) > i && g.Disposal[i] == gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+	return sheet, sizes, nil
+}
+
+func contactSheet(args []string) error {
+	fs := flag.NewFlagSet("contact-sheet", flag.ExitOnError)
+	out := fs.String("o", "sheet.png", "output PNG `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: quantize contact-sheet [-o file] input.gif")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	in, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	g, err := gif.DecodeAll(in)
+	if err != nil {
+		return err
+	}
+	sheet, sizes, err := renderSheet(g)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for i, size := range sizes {
+		fmt.Printf("frame %d: %d colors, %d bytes\n", i, len(g.Image[i].Palette), size)
+		total += size
+	}
+	fmt.Printf("total: %d frames, %d bytes\n", len(sizes), total)
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, sheet); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
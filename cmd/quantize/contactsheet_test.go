@@ -0,0 +1,38 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestRenderSheet(t *testing.T) {
+	p := color.Palette{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 0, 0, 255}}
+	g := &gif.GIF{Config: image.Config{Width: 16, Height: 16}}
+	for i := 0; i < 5; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 16, 16), p)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(j % (i + 1) % 2)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	sheet, sizes, err := renderSheet(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != 5 || sizes[0] <= 0 {
+		t.Fatalf("Expected 5 frame sizes, got %v", sizes)
+	}
+	cellW, cellH := 16+gap, 16+stripHeight+barHeight+gap
+	if b := sheet.Bounds(); b.Dx() != 3*cellW+gap || b.Dy() != 2*cellH+gap {
+		t.Fatalf("Expected a 3x2 grid, got %v", b)
+	}
+	if c := sheet.RGBAAt(gap+2, gap+16+1); c != p[0] {
+		t.Fatalf("Expected the first palette swatch below the frame, got %v", c)
+	}
+	if _, _, err := renderSheet(&gif.GIF{}); err == nil {
+		t.Fatal("Expected an error for an empty GIF")
+	}
+}
@@ -0,0 +1,49 @@
+// Command quantize provides tools for inspecting palettes and quantized images
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a subcommand run with its own arguments
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"contact-sheet", "render an animated GIF's frames, palettes and sizes to a PNG", contactSheet},
+	{"compare", "render a heat map of the error between an image and its quantized version", compare},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: quantize <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == flag.Arg(0) {
+			if err := c.run(flag.Args()[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "quantize %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "quantize: unknown command %q\n", flag.Arg(0))
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,120 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// SplitPalette holds separate palettes for color and alpha, for targets that store alpha in its own indexed plane
+type SplitPalette struct {
+	// Opaque colors, chosen from the unpremultiplied colors of visible pixels
+	Color color.Palette
+	// Alpha levels, each a color.Alpha
+	Alpha color.Palette
+}
+
+// unpremultiply returns the opaque color of a premultiplied pixel
+func unpremultiply(c color.RGBA) color.RGBA {
+	if c.A == 0 || c.A == 255 {
+		return color.RGBA{c.R, c.G, c.B, 255}
+	}
+	return color.RGBA{
+		uint8((uint32(c.R)*255 + uint32(c.A)/2) / uint32(c.A)),
+		uint8((uint32(c.G)*255 + uint32(c.A)/2) / uint32(c.A)),
+		uint8((uint32(c.B)*255 + uint32(c.A)/2) / uint32(c.A)),
+		255,
+	}
+}
+
+// premultiply returns the premultiplied form of a straight color
+func premultiply(c color.RGBA) color.RGBA {
+	if c.A == 255 {
+		return c
+	}
+	return color.RGBA{
+		uint8((uint32(c.R)*uint32(c.A) + 127) / 255),
+		uint8((uint32(c.G)*uint32(c.A) + 127) / 255),
+		uint8((uint32(c.B)*uint32(c.A) + 127) / 255),
+		c.A,
+	}
+}
+
+// straightAt returns the straight 8-bit color of a pixel along with its alpha. NRGBA images are read directly, while
+// premultiplied images are divided by their alpha. YCbCr images yield raw Y'CbCr values in R, G and B.
+func straightAt(m image.Image, x int, y int) color.RGBA {
+	switch i := m.(type) {
+	case *image.NRGBA:
+		ci := i.PixOffset(x, y)
+		return color.RGBA{i.Pix[ci+0], i.Pix[ci+1], i.Pix[ci+2], i.Pix[ci+3]}
+	case *image.NRGBA64:
+		ci := i.PixOffset(x, y)
+		return color.RGBA{i.Pix[ci+0], i.Pix[ci+2], i.Pix[ci+4], i.Pix[ci+6]}
+	}
+	c := colorAt(m, x, y)
+	if c.A == 0 || c.A == 255 {
+		return c
+	}
+	s := unpremultiply(c)
+	s.A = c.A
+	return s
+}
+
+// QuantizeSplitAlpha quantizes the colors of m to numColors opaque entries and its alpha channel independently to
+// alphaLevels values. Fully transparent pixels don't contribute to the color palette.
+func (q MedianCutQuantizer) QuantizeSplitAlpha(m image.Image, numColors int, alphaLevels int) SplitPalette {
+	b := m.Bounds()
+	colors := q.getBucket(b.Dx() * b.Dy() * 2)
+	alphas := make(colorBucket, 0, 256)
+	var alphaHist [256]uint32
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			alphaHist[c.A]++
+			if c.A != 0 {
+				colors.add(unpremultiply(c), 1)
+			}
+		}
+	}
+	bucket := colors[:0]
+	for _, c := range colors {
+		if c.p != 0 {
+			bucket = append(bucket, c)
+		}
+	}
+	defer q.putBucket(bucket)
+	for a, n := range alphaHist {
+		if n != 0 {
+			alphas = append(alphas, colorPriority{n, color.RGBA{uint8(a), uint8(a), uint8(a), 255}})
+		}
+	}
+
+	opaque := q
+	opaque.AddTransparent, opaque.MaxColors, opaque.AlphaThreshold = false, 0, 0
+	s := SplitPalette{Color: opaque.quantizeSlice(make(color.Palette, 0, numColors), bucket)}
+	levels := MedianCutQuantizer{Aggregation: Mean}
+	for _, c := range levels.palettize(nil, levels.bucketize(alphas, alphaLevels)) {
+		s.Alpha = append(s.Alpha, color.Alpha{c.(color.RGBA).R})
+	}
+	return s
+}
+
+// Index returns the indices of the color and alpha entries closest to c
+func (s SplitPalette) Index(c color.Color) (colorIndex int, alphaIndex int) {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return s.Color.Index(unpremultiply(rgba)), s.Alpha.Index(color.Alpha{rgba.A})
+}
+
+// Map maps each pixel of m onto the split palettes, returning the color and alpha index planes
+func (s SplitPalette) Map(m image.Image) (colors *image.Paletted, alpha *image.Paletted) {
+	b := m.Bounds()
+	colors = image.NewPaletted(b, s.Color)
+	alpha = image.NewPaletted(b, s.Alpha)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			ci, ai := s.Index(rgbaAt(m, x, y))
+			colors.SetColorIndex(x, y, uint8(ci))
+			alpha.SetColorIndex(x, y, uint8(ai))
+		}
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestQuantizeSplitAlpha(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x * 16), 0, uint8(255 - x*16), uint8(y * 17)})
+		}
+	}
+	s := MedianCutQuantizer{Aggregation: Mean}.QuantizeSplitAlpha(m, 4, 4)
+	if len(s.Color) != 4 || len(s.Alpha) != 4 {
+		t.Fatalf("Expected 4 colors and 4 alpha levels, got %d and %d", len(s.Color), len(s.Alpha))
+	}
+	for _, c := range s.Color {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			t.Fatalf("Expected opaque colors, got %v", c)
+		}
+	}
+	colors, alpha := s.Map(m)
+	if ai := alpha.ColorIndexAt(0, 15); s.Alpha[ai].(color.Alpha).A < 200 {
+		t.Fatalf("Expected an opaque pixel to map to a high alpha level, got %v", s.Alpha[ai])
+	}
+	if ci := colors.ColorIndexAt(15, 15); s.Color[ci].(color.RGBA).R < 128 {
+		t.Fatalf("Expected a red pixel to map to a red entry, got %v", s.Color[ci])
+	}
+}
+
+func TestStraightAlpha(t *testing.T) {
+	for _, m := range []image.Image{image.NewNRGBA(image.Rect(0, 0, 16, 16)), image.NewRGBA(image.Rect(0, 0, 16, 16))} {
+		d := m.(draw.Image)
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				a := uint8(255)
+				if x < 8 {
+					a = 64 // An antialiased edge of the same red
+				}
+				d.Set(x, y, color.NRGBA{240, 20, 20, a})
+			}
+		}
+		p := MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 1), m)
+		if c := p[0].(color.RGBA); c.R > 200 {
+			t.Fatalf("Expected premultiplied edges to darken the entry, got %v", c)
+		}
+		p = MedianCutQuantizer{Aggregation: Mean, StraightAlpha: true}.Quantize(make(color.Palette, 0, 1), m)
+		if c := p[0].(color.RGBA); sqDiff(c, color.RGBA{240, 20, 20, 255}) > 12 || c.A != 255 {
+			t.Fatalf("Expected straight edges to keep the entry red, got %v", c)
+		}
+		p = MedianCutQuantizer{Aggregation: Mean, StraightAlpha: true, QuantizeAlpha: true}.Quantize(make(color.Palette, 0, 2), m)
+		for _, e := range p {
+			c := e.(color.RGBA)
+			if c.R > c.A || sqDiff(unpremultiply(c), color.RGBA{240, 20, 20, 255}) > 12 {
+				t.Fatalf("Expected premultiplied red entries, got %v", c)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package quantize
+
+// Arena is caller-owned memory from which histograms are carved, for servers quantizing many small images per
+// request. Buckets are never returned individually; the whole arena is released at once with Reset, so no
+// per-image garbage reaches the collector. Requests that outgrow the arena fall back to ordinary allocations.
+// An Arena must not be used by more than one goroutine at a time.
+type Arena struct {
+	buf  colorBucket
+	used int
+}
+
+// NewArena creates an arena large enough to histogram images totalling the given number of pixels
+func NewArena(pixels int) *Arena {
+	return &Arena{buf: make(colorBucket, pixels*2)}
+}
+
+// Reset releases every bucket carved from the arena. Palettes produced while using the arena remain valid.
+func (a *Arena) Reset() {
+	a.used = 0
+}
+
+// getBucket carves a zeroed bucket of length c from the arena
+func (a *Arena) getBucket(c int) colorBucket {
+	if a.used+c > len(a.buf) {
+		return make(colorBucket, c)
+	}
+	slice := a.buf[a.used : a.used+c : a.used+c]
+	a.used += c
+	for i := range slice {
+		slice[i] = colorPriority{}
+	}
+	return slice
+}
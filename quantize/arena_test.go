@@ -0,0 +1,42 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	b := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range a.Pix {
+		a.Pix[i] = uint8(i)
+		b.Pix[i] = uint8(i * 3)
+	}
+	arena := NewArena(2 * 64)
+	q := MedianCutQuantizer{Aggregation: Mean, Arena: arena}
+	pa := q.Quantize(make(color.Palette, 0, 16), a)
+	pb := q.Quantize(make(color.Palette, 0, 16), b)
+	if arena.used != len(arena.buf) {
+		t.Fatalf("Expected both histograms to be carved from the arena, used %d of %d", arena.used, len(arena.buf))
+	}
+
+	// Exceeding the arena falls back to ordinary allocation
+	pc := q.Quantize(make(color.Palette, 0, 16), a)
+	for i := range pa {
+		if pa[i] != pc[i] {
+			t.Fatal("Arena overflow changed the palette")
+		}
+	}
+
+	arena.Reset()
+	pb2 := q.Quantize(make(color.Palette, 0, 16), b)
+	if arena.used != 2*64 {
+		t.Fatal("Reset didn't release the arena")
+	}
+	for i := range pb {
+		if pb[i] != pb2[i] {
+			t.Fatal("Reusing the arena changed the palette")
+		}
+	}
+}
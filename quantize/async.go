@@ -0,0 +1,44 @@
+package quantize
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// QuantizeAsync immediately returns a low-effort preview palette built from a downsampled copy of m, then refines
+// the preview with k-means against the full image histogram in a new goroutine. The refined palette, a separate
+// slice from the preview, is sent on the returned channel, which is closed once refinement finishes or ctx is done.
+// As with PyramidQuantizer, reserved colors and the transparent entry are left unrefined. m must not be modified
+// until the channel is closed.
+func (q KMeansQuantizer) QuantizeAsync(ctx context.Context, p color.Palette, m image.Image) (color.Palette, <-chan color.Palette) {
+	start := len(p)
+	_, addTransparent := q.MedianCut.target(p)
+	_, reserved := q.MedianCut.reserve(p[:start:start])
+	preview := PyramidQuantizer{
+		MedianCut:   q.MedianCut,
+		Levels:      3,
+		SampleStep:  16,
+		Convergence: Convergence{MaxIterations: 1},
+	}.Quantize(p, m)
+	generated := q.MedianCut.generatedEntries(preview, start, reserved, addTransparent)
+	// The caller may change the preview as soon as it is returned
+	result := append(color.Palette(nil), preview...)
+
+	refined := make(chan color.Palette, 1)
+	go func() {
+		defer close(refined)
+		// The caller may keep using its buffers while refinement runs
+		q.MedianCut.Scratch, q.MedianCut.Arena = nil, nil
+		bucket := q.MedianCut.buildBucket(m)
+		defer bpool.Put(bucket)
+		if ctx.Err() != nil {
+			return
+		}
+		q.MedianCut.refine(result, generated, bucket, q.Convergence)
+		if ctx.Err() == nil {
+			refined <- result
+		}
+	}()
+	return preview, refined
+}
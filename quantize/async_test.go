@@ -0,0 +1,41 @@
+package quantize
+
+import (
+	"context"
+	"image/color"
+	"testing"
+)
+
+func TestQuantizeAsync(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := KMeansQuantizer{}
+	preview, refined := q.QuantizeAsync(context.Background(), make([]color.Color, 0, 32), i)
+	if len(preview) != 32 {
+		t.Fatalf("Expected 32 preview colors, got %d", len(preview))
+	}
+	p, ok := <-refined
+	if !ok || len(p) != 32 {
+		t.Fatal("Refined palette was not delivered")
+	}
+	t.Logf("Preview error %f, refined error %f", Quality(preview, i).MeanError, Quality(p, i).MeanError)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, refined = q.QuantizeAsync(ctx, make([]color.Color, 0, 32), i)
+	if _, ok := <-refined; ok {
+		t.Fatal("Refined palette was delivered after cancellation")
+	}
+}
+
+func TestQuantizeAsyncTransparent(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := KMeansQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true, PlaceTransparent: true}}
+	preview, refined := q.QuantizeAsync(context.Background(), make([]color.Color, 0, 16), i)
+	if q.MedianCut.TransparentEntry(preview) != 0 {
+		t.Fatalf("Expected the preview's transparent entry at index 0, got %v", preview)
+	}
+	preview[0] = color.RGBA{255, 0, 0, 255}
+	if p := <-refined; len(p) != 16 || q.MedianCut.TransparentEntry(p) != 0 {
+		t.Fatalf("Expected the refined transparent entry to stay at index 0, got %v", p)
+	}
+}
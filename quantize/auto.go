@@ -0,0 +1,87 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// autoCoverage is the share of pixels the most frequent colors must cover for an image to be treated as synthetic
+const autoCoverage = 0.98
+
+// autoNarrowSpread is the RGB standard deviation below which an image's colors are too close together for Wu's
+// 5-bit grid, so median cut is used instead
+const autoNarrowSpread = 24
+
+// AutoQuantizer implements the go draw.Quantizer interface by inspecting the image histogram and dispatching to the
+// quantizer best suited to it. Screenshots and other synthetic images whose most frequent colors cover nearly every
+// pixel use PopularityQuantizer, keeping their colors exact. Images whose colors span a narrow range use median cut
+// with exact weighted medians, and other photographic images use WuQuantizer.
+type AutoQuantizer struct {
+	// Histogram and transparency options shared by every quantizer. If AutoTransparent is set, it applies whichever
+	// quantizer is chosen
+	MedianCut MedianCutQuantizer
+}
+
+// choose returns the quantizer suited to a histogram when numColors colors are requested
+func (q AutoQuantizer) choose(bucket colorBucket, numColors int) draw.Quantizer {
+	if numColors <= 0 || len(bucket) <= numColors {
+		return PopularityQuantizer{q.MedianCut}
+	}
+	counts := make([]uint32, len(bucket))
+	var total float64
+	for i, c := range bucket {
+		counts[i] = c.p
+		total += float64(c.p)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] > counts[j] })
+	var covered float64
+	for _, n := range counts[:numColors] {
+		covered += float64(n)
+	}
+	if covered >= autoCoverage*total {
+		return PopularityQuantizer{q.MedianCut}
+	}
+	if math.Sqrt(bucket.variance()/total) < autoNarrowSpread {
+		mc := q.MedianCut
+		mc.Partition = WeightedMedian
+		return mc
+	}
+	return WuQuantizer{q.MedianCut}
+}
+
+// resolve applies transparency detection for m, if enabled
+func (q AutoQuantizer) resolve(m image.Image) AutoQuantizer {
+	if q.MedianCut.AutoTransparent {
+		q.MedianCut.AddTransparent, q.MedianCut.AlphaThreshold = DetectTransparency(m)
+		q.MedianCut.AutoTransparent = false
+	}
+	return q
+}
+
+// Select returns the quantizer that Quantize would use for m with a palette of numColors colors
+func (q AutoQuantizer) Select(m image.Image, numColors int) draw.Quantizer {
+	q = q.resolve(m)
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	target, _ := q.MedianCut.target(make(color.Palette, 0, numColors))
+	return q.choose(bucket, target)
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q AutoQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	q = q.resolve(m)
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	numColors, _ := q.MedianCut.target(p)
+	switch chosen := q.choose(bucket, numColors).(type) {
+	case PopularityQuantizer:
+		return chosen.quantizeBucket(p, bucket)
+	case WuQuantizer:
+		return chosen.quantizeBucket(p, bucket)
+	default:
+		return chosen.(MedianCutQuantizer).quantizeSlice(p, bucket)
+	}
+}
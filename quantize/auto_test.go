@@ -0,0 +1,57 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestAutoQuantizer(t *testing.T) {
+	photo := openTestImage(t, "test_image.jpg")
+	if _, ok := (AutoQuantizer{}).Select(photo, 64).(WuQuantizer); !ok {
+		t.Fatal("Expected a photo to use Wu")
+	}
+	p := AutoQuantizer{}.Quantize(make(color.Palette, 0, 64), photo)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+
+	// A screenshot-like image of a few flat colors with a little antialiasing
+	screen := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < 30 {
+				c = color.RGBA{30, 30, 120, 255}
+			}
+			if y == 50 {
+				c = color.RGBA{uint8(x), uint8(x), uint8(x), 255}
+			}
+			screen.SetRGBA(x, y, c)
+		}
+	}
+	if _, ok := (AutoQuantizer{}).Select(screen, 16).(PopularityQuantizer); !ok {
+		t.Fatal("Expected a screenshot to use popularity")
+	}
+	if p := (AutoQuantizer{}).Quantize(make(color.Palette, 0, 16), screen); p.Index(color.RGBA{30, 30, 120, 255}) != 1 {
+		t.Fatalf("Expected the exact flat colors, got %v", p)
+	}
+
+	// A gradient spanning a narrow range
+	narrow := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			narrow.SetRGBA(x, y, color.RGBA{uint8(100 + x/8), uint8(100 + y/8), 100, 255})
+		}
+	}
+	if _, ok := (AutoQuantizer{}).Select(narrow, 16).(MedianCutQuantizer); !ok {
+		t.Fatal("Expected a narrow gradient to use median cut")
+	}
+
+	q := AutoQuantizer{MedianCut: MedianCutQuantizer{AutoTransparent: true}}
+	sprite := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	sprite.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 255})
+	if p := q.Quantize(make(color.Palette, 0, 4), sprite); len(p) != 2 || q.MedianCut.TransparentEntry(p) != 1 {
+		t.Fatalf("Expected a red entry and a transparent entry, got %v", p)
+	}
+}
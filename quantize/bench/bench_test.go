@@ -30,7 +30,7 @@ func getImage(b *testing.B) (m image.Image) {
 
 func BenchmarkQuantize(b *testing.B) {
 	m := getImage(b)
-	q := quantize.MedianCutQuantizer{quantize.Mean, nil, false}
+	q := quantize.MedianCutQuantizer{Aggregation: quantize.Mean}
 	for i := 0; i < b.N; i++ {
 		q.Quantize(make([]color.Color, 0, 256), m)
 	}
@@ -1,6 +1,10 @@
 package quantize
 
-import "image/color"
+import (
+	"image/color"
+	"math"
+	"sort"
+)
 
 type colorAxis uint8
 
@@ -9,6 +13,7 @@ const (
 	red colorAxis = iota
 	green
 	blue
+	alpha
 )
 
 type colorPriority struct {
@@ -22,15 +27,52 @@ func (c colorPriority) axis(span colorAxis) uint8 {
 		return c.R
 	case green:
 		return c.G
-	default:
+	case blue:
 		return c.B
+	default:
+		return c.A
 	}
 }
 
 type colorBucket []colorPriority
 
-func (cb colorBucket) partition() (colorBucket, colorBucket) {
-	mean, span := cb.span()
+// addPriority adds two priorities, saturating rather than wrapping so large solid regions can't lose their weight
+func addPriority(a, b uint32) uint32 {
+	if a > math.MaxUint32-b {
+		return math.MaxUint32
+	}
+	return a + b
+}
+
+// mulPriority multiplies two priorities, saturating rather than wrapping
+func mulPriority(a, b uint32) uint32 {
+	if p := uint64(a) * uint64(b); p < math.MaxUint32 {
+		return uint32(p)
+	}
+	return math.MaxUint32
+}
+
+// add accumulates priority for a color in a sparse, open-addressed bucket
+func (cb colorBucket) add(c color.RGBA, priority uint32) {
+	size := len(cb)
+	index := int(c.R)<<16 | int(c.G)<<8 | int(c.B)
+	for i := 1; ; i++ {
+		p := &cb[index%size]
+		if p.p == 0 || p.RGBA == c {
+			*p = colorPriority{addPriority(p.p, priority), c}
+			return
+		}
+		index += 1 + i
+	}
+}
+
+// partition splits the bucket around the weighted split point of its widest axis, scaling axis widths by weights
+func (cb colorBucket) partition(weights [4]uint32) (colorBucket, colorBucket) {
+	mean, span, width := cb.span(weights)
+	if width == 0 {
+		// Every color shares the split axis value, so there is nothing to divide around
+		return cb.populationPartition()
+	}
 	left, right := 0, len(cb)-1
 	for left < right {
 		cb[left], cb[right] = cb[right], cb[left]
@@ -50,6 +92,72 @@ func (cb colorBucket) partition() (colorBucket, colorBucket) {
 	return cb[:left], cb[left:]
 }
 
+// medianPartition splits the bucket at the exact weighted median of its widest axis
+func (cb colorBucket) medianPartition(weights [4]uint32) (colorBucket, colorBucket) {
+	_, span, _ := cb.span(weights)
+	cb.sortAxis(span)
+	return cb.populationPartition()
+}
+
+// populationPartition splits the bucket in its current order so that each side holds about half of the priority
+func (cb colorBucket) populationPartition() (colorBucket, colorBucket) {
+	var total uint64
+	for _, c := range cb {
+		total += uint64(c.p)
+	}
+	var counted uint64
+	for i, c := range cb[:len(cb)-1] {
+		counted += uint64(c.p)
+		if counted*2 >= total {
+			return cb[:i+1], cb[i+1:]
+		}
+	}
+	return cb[:len(cb)-1], cb[len(cb)-1:]
+}
+
+// sortAxis sorts the bucket by the given axis using an in-place counting sort
+func (cb colorBucket) sortAxis(axis colorAxis) {
+	var next, end [256]int
+	for _, c := range cb {
+		end[c.axis(axis)]++
+	}
+	var pos int
+	for v, n := range end {
+		next[v] = pos
+		pos += n
+		end[v] = pos
+	}
+	for v := range next {
+		for next[v] < end[v] {
+			dest := cb[next[v]].axis(axis)
+			if int(dest) != v {
+				cb[next[v]], cb[next[dest]] = cb[next[dest]], cb[next[v]]
+			}
+			next[dest]++
+		}
+	}
+}
+
+// colorKey packs a color into a single value ordering colors by red, green, blue and then alpha
+func colorKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// canonical sorts the bucket by color and combines repeated colors, giving it an order independent of how it was
+// accumulated
+func (cb colorBucket) canonical() colorBucket {
+	sort.Slice(cb, func(i, j int) bool { return colorKey(cb[i].RGBA) < colorKey(cb[j].RGBA) })
+	out := cb[:0]
+	for _, c := range cb {
+		if n := len(out); n > 0 && out[n-1].RGBA == c.RGBA {
+			out[n-1].p = addPriority(out[n-1].p, c.p)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
 func (cb colorBucket) mean() color.RGBA {
 	var r, g, b uint64
 	var p uint64
@@ -62,6 +170,38 @@ func (cb colorBucket) mean() color.RGBA {
 	return color.RGBA{uint8(r / p), uint8(g / p), uint8(b / p), 255}
 }
 
+// meanAlpha returns the priority-weighted mean alpha of the bucket
+func (cb colorBucket) meanAlpha() uint8 {
+	var a, p uint64
+	for _, c := range cb {
+		p += uint64(c.p)
+		a += uint64(c.A) * uint64(c.p)
+	}
+	return uint8(a / p)
+}
+
+// variance returns the total priority-weighted squared distance of the bucket's colors from their mean
+func (cb colorBucket) variance() float64 {
+	var sum, r, g, b float64
+	for _, c := range cb {
+		w := float64(c.p)
+		sum += w
+		r += w * float64(c.R)
+		g += w * float64(c.G)
+		b += w * float64(c.B)
+	}
+	if sum == 0 {
+		return 0
+	}
+	r, g, b = r/sum, g/sum, b/sum
+	var v float64
+	for _, c := range cb {
+		dr, dg, db := float64(c.R)-r, float64(c.G)-g, float64(c.B)-b
+		v += float64(c.p) * (dr*dr + dg*dg + db*db)
+	}
+	return v
+}
+
 type constraint struct {
 	min  uint8
 	max  uint8
@@ -82,30 +222,44 @@ func (c *constraint) span() uint8 {
 	return c.max - c.min
 }
 
-func (cb colorBucket) span() (uint8, colorAxis) {
+// span finds the widest axis of the bucket after scaling by weights, returning the weighted split point along it and
+// its unscaled width
+func (cb colorBucket) span(weights [4]uint32) (uint8, colorAxis, uint8) {
 	var R, G, B constraint
 	R.min = 255
 	G.min = 255
 	B.min = 255
+	var A constraint
+	A.min = 255
 	var p uint64
 	for _, c := range cb {
 		R.update(c.R, c.p)
 		G.update(c.G, c.p)
 		B.update(c.B, c.p)
+		if weights[alpha] != 0 {
+			A.update(c.A, c.p)
+		}
 		p += uint64(c.p)
 	}
 	var toCount *constraint
 	var span colorAxis
-	if R.span() > G.span() && R.span() > B.span() {
+	r := uint32(R.span()) * weights[red]
+	g := uint32(G.span()) * weights[green]
+	b := uint32(B.span()) * weights[blue]
+	if r > g && r > b {
 		span = red
 		toCount = &R
-	} else if G.span() > B.span() {
+	} else if g > b {
 		span = green
 		toCount = &G
 	} else {
 		span = blue
 		toCount = &B
 	}
+	if weights[alpha] != 0 && uint32(A.span())*weights[alpha] > uint32(toCount.span())*weights[span] {
+		span = alpha
+		toCount = &A
+	}
 	var counted uint64
 	var i int
 	var c uint64
@@ -115,5 +269,5 @@ func (cb colorBucket) span() (uint8, colorAxis) {
 		}
 		counted += c
 	}
-	return uint8(i), span
+	return uint8(i), span, toCount.span()
 }
@@ -0,0 +1,154 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomBucket(r *rand.Rand, n int) colorBucket {
+	cb := make(colorBucket, n)
+	for i := range cb {
+		cb[i] = colorPriority{uint32(r.Intn(100) + 1), color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}}
+	}
+	return cb
+}
+
+func TestSortAxis(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cb := randomBucket(r, 1000)
+	cb.sortAxis(green)
+	for i := 1; i < len(cb); i++ {
+		if cb[i-1].G > cb[i].G {
+			t.Fatal("Bucket is not sorted along the axis")
+		}
+	}
+}
+
+func TestMedianPartition(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	cb := randomBucket(r, 1000)
+	var total uint64
+	for _, c := range cb {
+		total += uint64(c.p)
+	}
+	left, right := cb.medianPartition(RGB.weights())
+	if len(left) == 0 || len(right) == 0 || len(left)+len(right) != len(cb) {
+		t.Fatal("Partition produced an empty or incomplete split")
+	}
+	var counted uint64
+	for _, c := range left[:len(left)-1] {
+		counted += uint64(c.p)
+	}
+	if counted*2 >= total {
+		t.Fatal("Partition split past the weighted median")
+	}
+}
+
+func TestFlatPartition(t *testing.T) {
+	cb := make(colorBucket, 100)
+	for i := range cb {
+		cb[i] = colorPriority{1, color.RGBA{10, 20, 30, uint8(i)}}
+	}
+	left, right := cb.partition(RGB.weights())
+	if len(left) != 50 || len(right) != 50 {
+		t.Fatalf("Expected a population split of a flat bucket, got %d and %d", len(left), len(right))
+	}
+}
+
+func FuzzPartition(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1})
+	f.Add([]byte{5, 0, 0, 255, 5, 1, 0, 0, 5, 2, 0, 0, 5, 3, 0, 0})
+	f.Add([]byte{0, 0, 0, 255, 255, 255, 255, 0, 0, 0, 1, 0, 0, 0, 2, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cb := make(colorBucket, 0, len(data)/4)
+		for i := 0; i+4 <= len(data); i += 4 {
+			cb = append(cb, colorPriority{uint32(data[i+3]) + 1, color.RGBA{data[i], data[i+1], data[i+2], 255}})
+		}
+		if len(cb) < 3 {
+			return
+		}
+		for _, part := range []func([4]uint32) (colorBucket, colorBucket){cb.partition, cb.medianPartition} {
+			left, right := part(YCbCr.weights())
+			if len(left) == 0 || len(right) == 0 || len(left)+len(right) != len(cb) {
+				t.Fatalf("Partition of %d colors produced %d and %d", len(cb), len(left), len(right))
+			}
+		}
+	})
+}
+
+func TestBucketizeCount(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	flat := make(colorBucket, 64)
+	for i := range flat {
+		flat[i] = colorPriority{uint32(i%3 + 1), color.RGBA{7, 7, uint8(i), 255}}
+	}
+	for _, q := range []MedianCutQuantizer{
+		{Partition: ApproximateMedian},
+		{Partition: WeightedMedian},
+		{Split: LargestVariance},
+		{Split: LargestProduct, Partition: WeightedMedian},
+	} {
+		for _, n := range []int{0, 1, 2, 3, 17, 64, 100} {
+			for _, cb := range []colorBucket{randomBucket(r, 50), flat} {
+				expected := n
+				if len(cb) < n {
+					expected = len(cb)
+				}
+				if got := len(q.bucketize(cb, n)); got != expected {
+					t.Fatalf("Expected %d buckets from %d colors, got %d", expected, len(cb), got)
+				}
+			}
+		}
+	}
+}
+
+func TestMinBucketWeight(t *testing.T) {
+	cb := colorBucket{
+		{1000, color.RGBA{200, 0, 0, 255}},
+		{1000, color.RGBA{0, 200, 0, 255}},
+		{3, color.RGBA{0, 0, 200, 255}},
+		{2, color.RGBA{0, 0, 220, 255}},
+		{1, color.RGBA{0, 0, 240, 255}},
+	}
+	for _, split := range []SplitStrategy{LongestAxis, LargestVariance} {
+		q := MedianCutQuantizer{Split: split, MinBucketWeight: 10}
+		buckets := q.bucketize(append(colorBucket(nil), cb...), 5)
+		for _, b := range buckets {
+			if len(b) > 1 && q.splittable(b) {
+				t.Fatalf("Expected bucketize to split every heavy bucket, got %v", buckets)
+			}
+		}
+		var light int
+		for _, b := range buckets {
+			if b[0].B != 0 {
+				light++
+			}
+		}
+		if light != 1 {
+			t.Fatalf("Expected the light colors to share a single bucket, got %d", light)
+		}
+	}
+}
+
+func TestPrioritySaturates(t *testing.T) {
+	cb := make(colorBucket, 4)
+	black := color.RGBA{0, 0, 0, 255}
+	cb.add(black, math.MaxUint32-1)
+	cb.add(black, 16)
+	for _, c := range cb {
+		if c.RGBA == black && c.p != math.MaxUint32 {
+			t.Fatalf("Expected the priority to saturate, got %d", c.p)
+		}
+	}
+
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.SetRGBA(0, 0, black)
+	m.SetRGBA(1, 0, black)
+	q := MedianCutQuantizer{Weighting: func(image.Image, int, int) uint32 { return 1 << 30 }, ToneBias: -1}
+	if got := q.buildBucket(m); len(got) != 1 || got[0].p != math.MaxUint32 {
+		t.Fatalf("Expected weighted, tone biased priorities to saturate, got %v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package quantize
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// CacheKey identifies an image's content together with the palette it is quantized into
+type CacheKey [sha256.Size]byte
+
+// Cache stores generated palette entries by the content of the quantized image, letting services that repeatedly
+// quantize the same assets skip histogramming. A cache must only be shared by quantizers with identical settings.
+type Cache interface {
+	// Get returns the colors previously stored for key, if any
+	Get(key CacheKey) (color.Palette, bool)
+	// Put stores the colors generated for key
+	Put(key CacheKey, colors color.Palette)
+}
+
+// cacheKey hashes the pixels of m, the existing palette entries and the number of colors to be generated
+func cacheKey(p color.Palette, m image.Image, numColors int) CacheKey {
+	h := sha256.New()
+	var buf [16]byte
+	bounds := m.Bounds()
+	binary.LittleEndian.PutUint32(buf[0:], uint32(bounds.Min.X))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(bounds.Min.Y))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(bounds.Max.X))
+	binary.LittleEndian.PutUint32(buf[12:], uint32(bounds.Max.Y))
+	h.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[0:], uint32(numColors))
+	h.Write(buf[:4])
+	for _, c := range p {
+		r, g, b, a := c.RGBA()
+		binary.LittleEndian.PutUint16(buf[0:], uint16(r))
+		binary.LittleEndian.PutUint16(buf[2:], uint16(g))
+		binary.LittleEndian.PutUint16(buf[4:], uint16(b))
+		binary.LittleEndian.PutUint16(buf[6:], uint16(a))
+		h.Write(buf[:8])
+	}
+	switch i := m.(type) {
+	case *image.RGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			start := i.PixOffset(bounds.Min.X, y)
+			h.Write(i.Pix[start : start+bounds.Dx()*4])
+		}
+	default:
+		row := make([]byte, bounds.Dx()*4)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := rgbaAt(m, x, y)
+				o := (x - bounds.Min.X) * 4
+				row[o], row[o+1], row[o+2], row[o+3] = c.R, c.G, c.B, c.A
+			}
+			h.Write(row)
+		}
+	}
+	var key CacheKey
+	h.Sum(key[:0])
+	return key
+}
+
+// MemoryCache is a Cache holding a bounded number of palettes in memory, evicting the least recently used.
+// It is safe for concurrent use.
+type MemoryCache struct {
+	size    int
+	m       sync.Mutex
+	order   *list.List
+	entries map[CacheKey]*list.Element
+}
+
+type memoryEntry struct {
+	key    CacheKey
+	colors color.Palette
+}
+
+// NewMemoryCache creates a MemoryCache holding at most size palettes
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{size: size, order: list.New(), entries: make(map[CacheKey]*list.Element)}
+}
+
+// Get returns the colors previously stored for key, if any
+func (c *MemoryCache) Get(key CacheKey) (color.Palette, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(memoryEntry).colors, true
+}
+
+// Put stores the colors generated for key
+func (c *MemoryCache) Put(key CacheKey, colors color.Palette) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value = memoryEntry{key, colors}
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(memoryEntry{key, colors})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(memoryEntry).key)
+		c.order.Remove(oldest)
+	}
+}
@@ -0,0 +1,58 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+type countingCache struct {
+	Cache
+	hits int
+}
+
+func (c *countingCache) Get(key CacheKey) (color.Palette, bool) {
+	p, ok := c.Cache.Get(key)
+	if ok {
+		c.hits++
+	}
+	return p, ok
+}
+
+func TestCache(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	cache := &countingCache{Cache: NewMemoryCache(2)}
+	q := MedianCutQuantizer{Cache: cache}
+	p := q.Quantize(make([]color.Color, 0, 64), i)
+	p2 := q.Quantize(make([]color.Color, 0, 64), i)
+	if cache.hits != 1 {
+		t.Fatalf("Expected one cache hit, got %d", cache.hits)
+	}
+	if len(p) != len(p2) {
+		t.Fatal("Cached palette differs")
+	}
+	for i := range p {
+		if p[i] != p2[i] {
+			t.Fatal("Cached palette differs")
+		}
+	}
+
+	q.Quantize(make([]color.Color, 0, 32), i)
+	if cache.hits != 1 {
+		t.Fatal("Cache hit for a different color count")
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := NewMemoryCache(1)
+	a := cacheKey(nil, image.NewRGBA(image.Rect(0, 0, 1, 1)), 1)
+	b := cacheKey(nil, image.NewRGBA(image.Rect(0, 0, 2, 1)), 1)
+	c.Put(a, color.Palette{color.Black})
+	c.Put(b, color.Palette{color.White})
+	if _, ok := c.Get(a); ok {
+		t.Fatal("Oldest entry was not evicted")
+	}
+	if _, ok := c.Get(b); !ok {
+		t.Fatal("Newest entry was evicted")
+	}
+}
@@ -0,0 +1,133 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// ChannelBitsQuantizer implements the go draw.Quantizer interface by choosing how many bits of the palette index
+// to give each channel, such as 3-3-2 or 2-4-2, and emitting the resulting uniform grid of colors. The split is
+// chosen to minimize the error for the image's histogram, for targets that require uniform per-channel levels.
+type ChannelBitsQuantizer struct {
+	// Histogram and transparency options
+	MedianCut MedianCutQuantizer
+}
+
+// channelLevels returns the evenly spaced 8-bit values of a channel quantized to the given number of bits. A channel
+// given no bits holds a single level at mean.
+func channelLevels(bits int, mean uint8) []uint8 {
+	if bits == 0 {
+		return []uint8{mean}
+	}
+	n := 1 << uint(bits)
+	levels := make([]uint8, n)
+	for i := range levels {
+		levels[i] = uint8((i*255 + (n-1)/2) / (n - 1))
+	}
+	return levels
+}
+
+// channelError returns the weighted squared error of quantizing a channel histogram to the given levels
+func channelError(hist *[256]uint64, levels []uint8) float64 {
+	var sum float64
+	for v, w := range hist {
+		if w == 0 {
+			continue
+		}
+		best := 256
+		for _, l := range levels {
+			d := v - int(l)
+			if d < 0 {
+				d = -d
+			}
+			if d < best {
+				best = d
+			}
+		}
+		sum += float64(w) * float64(best*best)
+	}
+	return sum
+}
+
+// gridPalette appends every combination of the given channel levels to p
+func gridPalette(p color.Palette, r, g, b []uint8) color.Palette {
+	for _, rv := range r {
+		for _, gv := range g {
+			for _, bv := range b {
+				p = append(p, color.RGBA{rv, gv, bv, 255})
+			}
+		}
+	}
+	return p
+}
+
+// allocate chooses the per-channel bits and levels minimizing error for a histogram within a palette of numColors
+func (q ChannelBitsQuantizer) allocate(bucket colorBucket, numColors int) (bits [3]int, levels [3][]uint8) {
+	var hists [3][256]uint64
+	var sums [3]uint64
+	var total uint64
+	for _, c := range bucket {
+		for axis, v := range [3]uint8{c.R, c.G, c.B} {
+			hists[axis][v] += uint64(c.p)
+			sums[axis] += uint64(v) * uint64(c.p)
+		}
+		total += uint64(c.p)
+	}
+	var means [3]uint8
+	if total > 0 {
+		for axis := range means {
+			means[axis] = uint8(sums[axis] / total)
+		}
+	}
+	budget := 0
+	for 1<<uint(budget+1) <= numColors && budget < 24 {
+		budget++
+	}
+
+	// Each channel's error depends only on its own bits, so tabulate them before searching the splits
+	var errs [3][9]float64
+	for axis := range errs {
+		for b := range errs[axis] {
+			errs[axis][b] = channelError(&hists[axis], channelLevels(b, means[axis]))
+		}
+	}
+	best := -1.0
+	for r := 0; r <= 8 && r <= budget; r++ {
+		for g := 0; g <= 8 && r+g <= budget; g++ {
+			b := budget - r - g
+			if b > 8 {
+				continue
+			}
+			if e := errs[0][r] + errs[1][g] + errs[2][b]; best < 0 || e < best {
+				best, bits = e, [3]int{r, g, b}
+			}
+		}
+	}
+	for axis := range levels {
+		levels[axis] = channelLevels(bits[axis], means[axis])
+	}
+	return
+}
+
+// Bits returns the number of bits given to the red, green and blue channels when quantizing m to numColors
+func (q ChannelBitsQuantizer) Bits(m image.Image, numColors int) (r, g, b int) {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	bits, _ := q.allocate(bucket, numColors)
+	return bits[0], bits[1], bits[2]
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q ChannelBitsQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	numColors, addTransparent := q.MedianCut.target(p)
+	if numColors > 0 {
+		_, levels := q.allocate(bucket, numColors)
+		p = gridPalette(p, levels[0], levels[1], levels[2])
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,33 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestChannelBits(t *testing.T) {
+	// A green gradient needs all of its bits in the green channel
+	m := image.NewRGBA(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		m.Set(x, 0, color.RGBA{0, uint8(x), 0, 255})
+	}
+	q := ChannelBitsQuantizer{}
+	if r, g, b := q.Bits(m, 256); r != 0 || g != 8 || b != 0 {
+		t.Fatalf("Expected 0-8-0 split for a green gradient, got %d-%d-%d", r, g, b)
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	r, g, b := q.Bits(i, 256)
+	t.Logf("Chose %d-%d-%d split", r, g, b)
+	p := q.Quantize(make([]color.Color, 0, 256), i)
+	if len(p) != 256 {
+		t.Fatalf("Expected 256 colors, got %d", len(p))
+	}
+
+	q = ChannelBitsQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}}
+	p = q.Quantize(make([]color.Color, 0, 256), i)
+	if len(p) != 129 {
+		t.Fatalf("Expected 128 grid colors and a transparent entry, got %d", len(p))
+	}
+}
@@ -0,0 +1,195 @@
+package quantize
+
+import (
+	"image/color"
+	"math"
+)
+
+// ColorSpace selects the coordinates buckets are split and aggregated in
+type ColorSpace uint8
+
+// ColorSpace constants
+const (
+	// RGB - Work directly on 8-bit sRGB values
+	RGB ColorSpace = iota
+	// YCbCr - Work on JPEG Y'CbCr values, giving luma twice the weight of chroma when choosing split axes
+	YCbCr
+	// Lab - Work on CIELAB coordinates under D65, so splits and means follow perceived lightness and hue. L* is
+	// scaled to 0-255 and a* and b* are offset by 128
+	Lab
+	// OKLab - Work on OKLab coordinates, which predict perceived differences better than CIELAB at a lower cost. L is
+	// scaled to 0-255 and a and b by 400 and offset by 128. Paletted also maps pixels to their nearest entry in OKLab
+	OKLab
+)
+
+// srgbLinear maps 8-bit sRGB channels to linear light
+var srgbLinear = func() (t [256]float64) {
+	for i := range t {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			t[i] = v / 12.92
+		} else {
+			t[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return
+}()
+
+// encodeSRGB converts a linear light channel to a rounded 8-bit sRGB value, clipping it to range
+func encodeSRGB(v float64) uint8 {
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Max(0, math.Min(255, math.Round(v*255))))
+}
+
+// D65 reference white
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// labF is the companding function of CIELAB
+func labF(t float64) float64 {
+	if t > 216.0/24389 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27*t + 16) / 116
+}
+
+// labFInv inverts labF
+func labFInv(t float64) float64 {
+	if t3 := t * t * t; t3 > 216.0/24389 {
+		return t3
+	}
+	return (116*t - 16) * 27 / 24389
+}
+
+// toLab returns the CIELAB coordinates of an sRGB color
+func toLab(c color.RGBA) (l, a, b float64) {
+	r, g, bl := srgbLinear[c.R], srgbLinear[c.G], srgbLinear[c.B]
+	x := labF((0.4124564*r + 0.3575761*g + 0.1804375*bl) / whiteX)
+	y := labF((0.2126729*r + 0.7151522*g + 0.0721750*bl) / whiteY)
+	z := labF((0.0193339*r + 0.1191920*g + 0.9503041*bl) / whiteZ)
+	return 116*y - 16, 500 * (x - y), 200 * (y - z)
+}
+
+// fromLab returns the sRGB color nearest to CIELAB coordinates, clipping it to the gamut
+func fromLab(l, a, b float64) (uint8, uint8, uint8) {
+	y := (l + 16) / 116
+	x := labFInv(y+a/500) * whiteX
+	z := labFInv(y-b/200) * whiteZ
+	y = labFInv(y) * whiteY
+	return encodeSRGB(3.2404542*x - 1.5371385*y - 0.4985314*z),
+		encodeSRGB(-0.9692660*x + 1.8760108*y + 0.0415560*z),
+		encodeSRGB(0.0556434*x - 0.2040259*y + 1.0572252*z)
+}
+
+// toOKLab returns the OKLab coordinates of an sRGB color
+func toOKLab(c color.RGBA) (l, a, b float64) {
+	r, g, bl := srgbLinear[c.R], srgbLinear[c.G], srgbLinear[c.B]
+	lc := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	mc := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sc := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+	return 0.2104542553*lc + 0.7936177850*mc - 0.0040720468*sc,
+		1.9779984951*lc - 2.4285922050*mc + 0.4505937099*sc,
+		0.0259040371*lc + 0.7827717662*mc - 0.8086757660*sc
+}
+
+// fromOKLab returns the sRGB color nearest to OKLab coordinates, clipping it to the gamut
+func fromOKLab(l, a, b float64) (uint8, uint8, uint8) {
+	lc := l + 0.3963377774*a + 0.2158037573*b
+	mc := l - 0.1055613458*a - 0.0638541728*b
+	sc := l - 0.0894841775*a - 1.2914855480*b
+	lc, mc, sc = lc*lc*lc, mc*mc*mc, sc*sc*sc
+	return encodeSRGB(4.0767416621*lc - 3.3077115913*mc + 0.2309699292*sc),
+		encodeSRGB(-1.2684380046*lc + 2.6097574011*mc - 0.3413193965*sc),
+		encodeSRGB(-0.0041960863*lc - 0.7034186147*mc + 1.7076147010*sc)
+}
+
+// okLabEntry is an opaque palette entry in OKLab
+type okLabEntry struct {
+	index   int
+	l, a, b float64
+}
+
+// okLabPalette holds the OKLab coordinates of the opaque entries of a palette for nearest entry searches
+type okLabPalette []okLabEntry
+
+func newOKLabPalette(p color.Palette) okLabPalette {
+	var o okLabPalette
+	for i, c := range p {
+		if rgba := color.RGBAModel.Convert(c).(color.RGBA); rgba.A == 255 {
+			l, a, b := toOKLab(rgba)
+			o = append(o, okLabEntry{i, l, a, b})
+		}
+	}
+	return o
+}
+
+// index returns the index of the opaque entry nearest to c in OKLab, preferring the lowest index among equals, or -1
+// if there is none
+func (o okLabPalette) index(c color.RGBA) int {
+	l, a, b := toOKLab(c)
+	best, bestDist := -1, math.Inf(1)
+	for _, e := range o {
+		dl, da, db := l-e.l, a-e.a, b-e.b
+		if d := dl*dl + da*da + db*db; d < bestDist {
+			best, bestDist = e.index, d
+		}
+	}
+	return best
+}
+
+// clip8 rounds a working space coordinate to 8 bits, clipping it to range
+func clip8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// encode converts an sRGB color to working space coordinates, keeping alpha
+func (s ColorSpace) encode(c color.RGBA) color.RGBA {
+	switch s {
+	case YCbCr:
+		c.R, c.G, c.B = color.RGBToYCbCr(c.R, c.G, c.B)
+	case Lab:
+		l, a, b := toLab(c)
+		c.R, c.G, c.B = clip8(l*2.55), clip8(a+128), clip8(b+128)
+	case OKLab:
+		l, a, b := toOKLab(c)
+		c.R, c.G, c.B = clip8(l*255), clip8(a*400+128), clip8(b*400+128)
+	}
+	return c
+}
+
+// decode converts working space coordinates back to an sRGB color, keeping alpha
+func (s ColorSpace) decode(c color.RGBA) color.RGBA {
+	switch s {
+	case YCbCr:
+		c.R, c.G, c.B = color.YCbCrToRGB(c.R, c.G, c.B)
+	case Lab:
+		c.R, c.G, c.B = fromLab(float64(c.R)/2.55, float64(c.G)-128, float64(c.B)-128)
+	case OKLab:
+		c.R, c.G, c.B = fromOKLab(float64(c.R)/255, (float64(c.G)-128)/400, (float64(c.B)-128)/400)
+	}
+	return c
+}
+
+// weights returns the relative importance of each working space axis when choosing where to split, followed by a
+// zero weight for alpha
+func (s ColorSpace) weights() [4]uint32 {
+	switch s {
+	case YCbCr:
+		return [4]uint32{2, 1, 1, 0}
+	case Lab:
+		// A step of L* spans 2.55 values, so weight it down to match a step of a* or b*
+		return [4]uint32{2, 5, 5, 0}
+	case OKLab:
+		// A step of L spans less of the space than a step of a or b
+		return [4]uint32{3, 2, 2, 0}
+	default:
+		return [4]uint32{1, 1, 1, 0}
+	}
+}
@@ -0,0 +1,95 @@
+package quantize
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestColorSpaceRoundTrip(t *testing.T) {
+	for _, s := range []ColorSpace{RGB, YCbCr, Lab} {
+		for _, c := range []color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}, {200, 100, 50, 128}} {
+			d := s.decode(s.encode(c))
+			if sqDiff(c, d) > 3 || d.A != c.A {
+				t.Fatalf("Color space %d round trip changed %v to %v", s, c, d)
+			}
+		}
+	}
+}
+
+func TestQuantizeYCbCr(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{ColorSpace: YCbCr}
+	p := q.Quantize(make(color.Palette, 0, 64), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+	ycbcr := Quality(p, i).MeanError
+	rgb := Quality(MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 64), i), i).MeanError
+	t.Logf("Mean error %f in YCbCr, %f in RGB", ycbcr, rgb)
+	if ycbcr > rgb*2 {
+		t.Fatalf("Expected YCbCr error %f to be comparable to RGB error %f", ycbcr, rgb)
+	}
+}
+
+func TestLab(t *testing.T) {
+	l, a, b := toLab(color.RGBA{255, 0, 0, 255})
+	if math.Abs(l-53.24) > 0.05 || math.Abs(a-80.09) > 0.05 || math.Abs(b-67.20) > 0.05 {
+		t.Fatalf("Unexpected Lab coordinates %f, %f, %f for red", l, a, b)
+	}
+	for v := 0; v < 256; v += 5 {
+		c := color.RGBA{uint8(v), uint8(255 - v), uint8(v * 7), 255}
+		if r, g, b := fromLab(toLab(c)); (color.RGBA{r, g, b, 255}) != c {
+			t.Fatalf("Lab round trip changed %v to %v", c, color.RGBA{r, g, b, 255})
+		}
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{ColorSpace: Lab, Aggregation: Mean}.Quantize(make(color.Palette, 0, 64), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+	lab := Quality(p, i).MeanError
+	rgb := Quality(MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 64), i), i).MeanError
+	t.Logf("Mean error %f in Lab, %f in RGB", lab, rgb)
+	if lab > rgb*2 {
+		t.Fatalf("Expected Lab error %f to be comparable to RGB error %f", lab, rgb)
+	}
+}
+
+func TestOKLab(t *testing.T) {
+	l, a, b := toOKLab(color.RGBA{255, 255, 255, 255})
+	if math.Abs(l-1) > 1e-4 || math.Abs(a) > 1e-4 || math.Abs(b) > 1e-4 {
+		t.Fatalf("Unexpected OKLab coordinates %f, %f, %f for white", l, a, b)
+	}
+	for v := 0; v < 256; v += 5 {
+		c := color.RGBA{uint8(v), uint8(255 - v), uint8(v * 7), 255}
+		if r, g, b := fromOKLab(toOKLab(c)); (color.RGBA{r, g, b, 255}) != c {
+			t.Fatalf("OKLab round trip changed %v to %v", c, color.RGBA{r, g, b, 255})
+		}
+		// The working space coordinates hold 8 bits, so allow a difference well below what is visible
+		if d := OKLab.decode(OKLab.encode(c)); (OKLabDistance{}).Distance(c, d) > 0.5 {
+			t.Fatalf("OKLab working space round trip changed %v to %v", c, d)
+		}
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{ColorSpace: OKLab, Aggregation: Mean}
+	out := q.Paletted(i, 32, nil)
+	if len(out.Palette) != 32 {
+		t.Fatalf("Expected 32 colors, got %d", len(out.Palette))
+	}
+	// Pixels map to the entry nearest in OKLab
+	metric := OKLabDistance{}
+	for y := 0; y < 40; y += 3 {
+		for x := 0; x < 40; x += 3 {
+			c := rgbaAt(i, x, y)
+			got := metric.Distance(c, out.Palette[out.ColorIndexAt(x, y)].(color.RGBA))
+			for _, e := range out.Palette {
+				if d := metric.Distance(c, e.(color.RGBA)); d < got-1e-9 {
+					t.Fatalf("Expected %v to map to its nearest OKLab entry", c)
+				}
+			}
+		}
+	}
+}
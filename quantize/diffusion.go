@@ -0,0 +1,230 @@
+package quantize
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+)
+
+// KernelWeight is the share of a pixel's error diffused into the neighbor at an offset from it
+type KernelWeight struct {
+	// The offset of the neighbor. DY must be positive, or zero with a positive DX, so only pixels that haven't been
+	// visited yet receive error
+	DX, DY int
+	// The share of the error, in units of the kernel's Divisor
+	Weight int32
+}
+
+// Kernel describes how error diffusion distributes each pixel's error among its neighbors, for rows scanned left
+// to right. Weights summing to less than Divisor discard the rest of the error.
+type Kernel struct {
+	Weights []KernelWeight
+	Divisor int32
+}
+
+// Classic error diffusion kernels
+var (
+	FloydSteinbergKernel = Kernel{[]KernelWeight{
+		{1, 0, 7},
+		{-1, 1, 3}, {0, 1, 5}, {1, 1, 1},
+	}, 16}
+	JarvisJudiceNinkeKernel = Kernel{[]KernelWeight{
+		{1, 0, 7}, {2, 0, 5},
+		{-2, 1, 3}, {-1, 1, 5}, {0, 1, 7}, {1, 1, 5}, {2, 1, 3},
+		{-2, 2, 1}, {-1, 2, 3}, {0, 2, 5}, {1, 2, 3}, {2, 2, 1},
+	}, 48}
+	StuckiKernel = Kernel{[]KernelWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+		{-2, 2, 1}, {-1, 2, 2}, {0, 2, 4}, {1, 2, 2}, {2, 2, 1},
+	}, 42}
+	BurkesKernel = Kernel{[]KernelWeight{
+		{1, 0, 8}, {2, 0, 4},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 8}, {1, 1, 4}, {2, 1, 2},
+	}, 32}
+	SierraKernel = Kernel{[]KernelWeight{
+		{1, 0, 5}, {2, 0, 3},
+		{-2, 1, 2}, {-1, 1, 4}, {0, 1, 5}, {1, 1, 4}, {2, 1, 2},
+		{-1, 2, 2}, {0, 2, 3}, {1, 2, 2},
+	}, 32}
+	SierraLiteKernel = Kernel{[]KernelWeight{
+		{1, 0, 2},
+		{-1, 1, 1}, {0, 1, 1},
+	}, 4}
+	// Atkinson diffuses only three quarters of the error, trading accuracy for higher contrast
+	AtkinsonKernel = Kernel{[]KernelWeight{
+		{1, 0, 1}, {2, 0, 1},
+		{-1, 1, 1}, {0, 1, 1}, {1, 1, 1},
+		{0, 2, 1},
+	}, 8}
+)
+
+// ErrorDiffusion implements the go draw.Drawer interface with error diffusion onto paletted images using any kernel.
+// Fully transparent pixels are mapped without dithering and stop error from spreading across them.
+// Destinations other than *image.Paletted are drawn without dithering.
+type ErrorDiffusion struct {
+	// The diffusion kernel. If it has no weights, FloydSteinbergKernel is used
+	Kernel Kernel
+	// How strongly dithering is suppressed near edges. 0 dithers uniformly, while larger values keep text and line
+	// art crisp by diffusing less error into pixels with a large local gradient.
+	EdgeFalloff float64
+	// The fraction of each pixel's error diffused into its neighbors, from 0 to 1. If zero, all of the error the
+	// kernel distributes is diffused
+	Strength float64
+	// Whether alternate rows are scanned right to left, mirroring the kernel
+	Serpentine bool
+	// Whether error is accumulated in linear light rather than between gamma-encoded sRGB values, which keeps
+	// dithered midtones from darkening at small palette sizes
+	Linear bool
+}
+
+var (
+	linearOnce sync.Once
+	toLinear   []uint16 // Maps 16-bit sRGB channels to linear light
+	fromLinear []uint16 // Maps 16-bit linear light channels to sRGB
+)
+
+// linearTables returns the tables converting 16-bit channels between sRGB and linear light, building them on first use
+func linearTables() ([]uint16, []uint16) {
+	linearOnce.Do(func() {
+		toLinear = make([]uint16, 1<<16)
+		fromLinear = make([]uint16, 1<<16)
+		for i := range toLinear {
+			v := float64(i) / 0xffff
+			var lin, enc float64
+			if v <= 0.04045 {
+				lin = v / 12.92
+			} else {
+				lin = math.Pow((v+0.055)/1.055, 2.4)
+			}
+			if v <= 0.0031308 {
+				enc = v * 12.92
+			} else {
+				enc = 1.055*math.Pow(v, 1/2.4) - 0.055
+			}
+			toLinear[i] = uint16(lin*0xffff + 0.5)
+			fromLinear[i] = uint16(enc*0xffff + 0.5)
+		}
+	})
+	return toLinear, fromLinear
+}
+
+// strength returns the fraction of incoming error diffused into a source pixel
+func (d ErrorDiffusion) strength(m image.Image, x int, y int) float64 {
+	s := 1.0
+	if d.Strength > 0 {
+		s = math.Min(d.Strength, 1)
+	}
+	if d.EdgeFalloff <= 0 {
+		return s
+	}
+	s *= 1 - d.EdgeFalloff*float64(gradient(m, x, y))/255
+	if s < 0 {
+		return 0
+	}
+	return s
+}
+
+// diffuse runs error diffusion over the pixels of src starting at sp, covering a rectangle the size of r. pick maps
+// each pixel of r, with diffused error applied, to the palette color chosen for it. Error is never diffused into or
+// out of fully transparent pixels.
+func (d ErrorDiffusion) diffuse(r image.Rectangle, src image.Image, sp image.Point, pick func(x, y int, c [4]int32) [4]int32) {
+	kernel := d.Kernel
+	if len(kernel.Weights) == 0 {
+		kernel = FloydSteinbergKernel
+	}
+	pad, depth := 0, 0
+	for _, k := range kernel.Weights {
+		if k.DX > pad {
+			pad = k.DX
+		}
+		if -k.DX > pad {
+			pad = -k.DX
+		}
+		if k.DY > depth {
+			depth = k.DY
+		}
+	}
+	// Error rows are padded by the kernel's reach on each side and hold error in units of the divisor
+	var lin, enc []uint16
+	if d.Linear {
+		lin, enc = linearTables()
+	}
+	w := r.Dx()
+	rows := make([][][4]int32, depth+1)
+	for i := range rows {
+		rows[i] = make([][4]int32, w+2*pad)
+	}
+	for y := 0; y < r.Dy(); y++ {
+		// Serpentine rows run right to left on odd rows, mirroring the kernel
+		dir := 1
+		if d.Serpentine && y%2 == 1 {
+			dir = -1
+		}
+		for i := 0; i < w; i++ {
+			x := i
+			if dir < 0 {
+				x = w - 1 - i
+			}
+			sx, sy := sp.X+x, sp.Y+y
+			cr, cg, cb, ca := src.At(sx, sy).RGBA()
+			c := [4]int32{int32(cr), int32(cg), int32(cb), int32(ca)}
+			if ca == 0 {
+				// Fully transparent pixels neither receive nor pass on error, so sprites don't gain halos
+				pick(r.Min.X+x, r.Min.Y+y, c)
+				continue
+			}
+			s := d.strength(src, sx, sy)
+			if lin != nil {
+				for ch := range c[:3] {
+					c[ch] = int32(lin[c[ch]])
+				}
+			}
+			for ch := range c {
+				c[ch] = clamp16(c[ch] + int32(s*float64(rows[0][x+pad][ch])/float64(kernel.Divisor)))
+			}
+			var chosen [4]int32
+			if lin != nil {
+				// Palette entries are chosen in sRGB, but error is measured in linear light
+				chosen = pick(r.Min.X+x, r.Min.Y+y, [4]int32{int32(enc[c[0]]), int32(enc[c[1]]), int32(enc[c[2]]), c[3]})
+				for ch := range chosen[:3] {
+					chosen[ch] = int32(lin[chosen[ch]])
+				}
+			} else {
+				chosen = pick(r.Min.X+x, r.Min.Y+y, c)
+			}
+			for ch := range c {
+				e := c[ch] - chosen[ch]
+				for _, k := range kernel.Weights {
+					rows[k.DY][x+pad+k.DX*dir][ch] += e * k.Weight
+				}
+			}
+		}
+		first := rows[0]
+		copy(rows, rows[1:])
+		for i := range first {
+			first[i] = [4]int32{}
+		}
+		rows[depth] = first
+	}
+}
+
+// Draw dithers the part of src starting at sp onto the rectangle r of dst
+func (d ErrorDiffusion) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	p, ok := dst.(*image.Paletted)
+	if !ok || len(p.Palette) == 0 {
+		draw.Draw(dst, r, src, sp, draw.Src)
+		return
+	}
+	r, sp = clipDraw(dst, r, src, sp)
+	if r.Empty() {
+		return
+	}
+	palette := entries16(p.Palette)
+	d.diffuse(r, src, sp, func(x, y int, c [4]int32) [4]int32 {
+		index := nearest(palette, c)
+		p.SetColorIndex(x, y, uint8(index))
+		return palette[index]
+	})
+}
@@ -0,0 +1,100 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestErrorDiffusionKernels(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	for _, k := range []Kernel{
+		FloydSteinbergKernel, JarvisJudiceNinkeKernel, StuckiKernel, BurkesKernel, SierraKernel, SierraLiteKernel,
+	} {
+		var sum int32
+		for _, w := range k.Weights {
+			sum += w.Weight
+			if w.DY < 0 || (w.DY == 0 && w.DX <= 0) {
+				t.Fatalf("Kernel %v diffuses error into a visited pixel", k)
+			}
+		}
+		if sum != k.Divisor {
+			t.Fatalf("Expected kernel weights to sum to %d, got %d", k.Divisor, sum)
+		}
+		for _, serpentine := range []bool{false, true} {
+			dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+			ErrorDiffusion{Kernel: k, Serpentine: serpentine}.Draw(dst, dst.Bounds(), src, image.Point{})
+			if mean := meanGray(dst); mean < 56 || mean > 72 {
+				t.Fatalf("Expected dithered mean near 64 with %d weights, got %f", len(k.Weights), mean)
+			}
+		}
+	}
+}
+
+func TestAtkinson(t *testing.T) {
+	// Atkinson discards a quarter of the error, so midtones are pushed towards the nearer extreme
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	ErrorDiffusion{Kernel: AtkinsonKernel}.Draw(dst, dst.Bounds(), src, image.Point{})
+	if mean := meanGray(dst); mean <= 0 || mean >= 64 {
+		t.Fatalf("Expected a dithered mean between 0 and 64, got %f", mean)
+	}
+}
+
+func TestErrorDiffusionDefaultKernel(t *testing.T) {
+	src := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 16), src)
+	a := image.NewPaletted(src.Bounds(), p)
+	FloydSteinberg{Serpentine: true}.Draw(a, a.Bounds(), src, src.Bounds().Min)
+	b := image.NewPaletted(src.Bounds(), p)
+	ErrorDiffusion{Serpentine: true}.Draw(b, b.Bounds(), src, src.Bounds().Min)
+	if string(a.Pix) != string(b.Pix) {
+		t.Fatal("Expected the zero kernel to match FloydSteinberg")
+	}
+}
+
+func TestErrorDiffusionTransparency(t *testing.T) {
+	// A gray sprite beside a transparent region dithers exactly as it would on its own
+	p := color.Palette{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{}}
+	sprite := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 16; x < 32; x++ {
+			sprite.Set(x, y, color.RGBA{100, 100, 100, 255})
+		}
+	}
+	for _, d := range []draw.Drawer{FloydSteinberg{}, ErrorDiffusion{Kernel: StuckiKernel}, ErrorDiffusion{Kernel: SierraKernel, Serpentine: true}} {
+		dst := image.NewPaletted(sprite.Rect, p)
+		d.Draw(dst, dst.Rect, sprite, image.Point{})
+		alone := image.NewPaletted(image.Rect(16, 0, 32, 32), p)
+		d.Draw(alone, alone.Rect, sprite, alone.Rect.Min)
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				want := uint8(2)
+				if x >= 16 {
+					want = alone.ColorIndexAt(x, y)
+				}
+				if got := dst.ColorIndexAt(x, y); got != want {
+					t.Fatalf("Expected index %d at (%d, %d), got %d", want, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestErrorDiffusionLinear(t *testing.T) {
+	// sRGB 128 is about 22% of the light of white, so linear dithering lights about 22% of pixels
+	src := image.NewUniform(color.RGBA{128, 128, 128, 255})
+	for _, d := range []draw.Drawer{FloydSteinberg{Linear: true}, ErrorDiffusion{Kernel: SierraKernel, Linear: true}} {
+		dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+		d.Draw(dst, dst.Bounds(), src, image.Point{})
+		if mean := meanGray(dst); mean < 48 || mean > 64 {
+			t.Fatalf("Expected dithered mean near 55, got %f", mean)
+		}
+	}
+	dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	FloydSteinberg{}.Draw(dst, dst.Bounds(), src, image.Point{})
+	if mean := meanGray(dst); mean < 120 || mean > 136 {
+		t.Fatalf("Expected gamma-space dithered mean near 128, got %f", mean)
+	}
+}
@@ -0,0 +1,184 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Distance measures how different two colors appear
+type Distance interface {
+	// Distance returns a non-negative difference between a and b, which is zero for equal colors
+	Distance(a, b color.RGBA) float64
+}
+
+// DistanceFunc adapts an ordinary function to the Distance interface
+type DistanceFunc func(a, b color.RGBA) float64
+
+// Distance calls f(a, b)
+func (f DistanceFunc) Distance(a, b color.RGBA) float64 {
+	return f(a, b)
+}
+
+// EuclideanRGB measures the straight-line distance between colors in 8-bit sRGB space
+type EuclideanRGB struct{}
+
+// Distance returns the Euclidean distance between a and b
+func (EuclideanRGB) Distance(a, b color.RGBA) float64 {
+	return math.Sqrt(float64(sqDiff(a, b)))
+}
+
+// OKLabDistance measures the straight-line distance between colors in OKLab, scaled so that black and white are 100
+// apart
+type OKLabDistance struct{}
+
+// Distance returns the OKLab distance between a and b
+func (OKLabDistance) Distance(a, b color.RGBA) float64 {
+	l1, a1, b1 := toOKLab(a)
+	l2, a2, b2 := toOKLab(b)
+	return 100 * math.Sqrt((l1-l2)*(l1-l2)+(a1-a2)*(a1-a2)+(b1-b2)*(b1-b2))
+}
+
+// ErrorMap returns the distance between each pixel of orig and the corresponding pixel of quantized, rounded and
+// clipped to 255, for quality gating and for weighting later passes towards poorly mapped regions. If metric is nil,
+// EuclideanRGB is used. Pixels outside the bounds of orig are zero.
+func ErrorMap(orig image.Image, quantized *image.Paletted, metric Distance) *image.Gray {
+	if metric == nil {
+		metric = EuclideanRGB{}
+	}
+	entries := make([]color.RGBA, len(quantized.Palette))
+	for i, c := range quantized.Palette {
+		entries[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	b := quantized.Bounds()
+	m := image.NewGray(b)
+	r := b.Intersect(orig.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			index := int(quantized.ColorIndexAt(x, y))
+			if index >= len(entries) {
+				continue
+			}
+			d := math.Round(metric.Distance(rgbaAt(orig, x, y), entries[index]))
+			m.Pix[m.PixOffset(x, y)] = uint8(math.Min(d, 255))
+		}
+	}
+	return m
+}
+
+// Redmean measures distance in sRGB with channel weights that follow the mean red level of the two colors, a cheap
+// approximation of perceived difference
+type Redmean struct{}
+
+// Distance returns the redmean weighted distance between a and b
+func (Redmean) Distance(a, b color.RGBA) float64 {
+	rmean := (float64(a.R) + float64(b.R)) / 2
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt((2+rmean/256)*dr*dr + 4*dg*dg + (2+(255-rmean)/256)*db*db)
+}
+
+// CIE76 measures the straight-line distance between colors in CIELAB, where a difference of about 2.3 is just
+// noticeable
+type CIE76 struct{}
+
+// Distance returns the CIE76 color difference between a and b
+func (CIE76) Distance(a, b color.RGBA) float64 {
+	l1, a1, b1 := toLab(a)
+	l2, a2, b2 := toLab(b)
+	return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+}
+
+// CIEDE2000 measures color difference with the CIEDE2000 formula, which corrects CIELAB for its uneven perception of
+// saturated colors and blues. It is the most accurate and slowest of the metrics
+type CIEDE2000 struct{}
+
+// Distance returns the CIEDE2000 color difference between a and b
+func (CIEDE2000) Distance(a, b color.RGBA) float64 {
+	l1, a1, b1 := toLab(a)
+	l2, a2, b2 := toLab(b)
+	return ciede2000(l1, a1, b1, l2, a2, b2)
+}
+
+// ciede2000 returns the CIEDE2000 difference between two CIELAB colors
+func ciede2000(l1, a1, b1, l2, a2, b2 float64) float64 {
+	const pow25to7 = 6103515625.0
+	rad := math.Pi / 180
+	cbar := (math.Hypot(a1, b1) + math.Hypot(a2, b2)) / 2
+	cbar7 := math.Pow(cbar, 7)
+	g := 0.5 * (1 - math.Sqrt(cbar7/(cbar7+pow25to7)))
+	a1, a2 = (1+g)*a1, (1+g)*a2
+	c1, c2 := math.Hypot(a1, b1), math.Hypot(a2, b2)
+	hue := func(a, b float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / rad
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1, h2 := hue(a1, b1), hue(a2, b2)
+
+	dl, dc := l2-l1, c2-c1
+	var dh float64
+	hbar := h1 + h2
+	if c1*c2 != 0 {
+		// Hue differences and means are taken the short way around the circle
+		dh = h2 - h1
+		switch {
+		case dh > 180:
+			dh -= 360
+		case dh < -180:
+			dh += 360
+		}
+		switch {
+		case math.Abs(h2-h1) <= 180:
+			hbar = (h1 + h2) / 2
+		case h1+h2 < 360:
+			hbar = (h1 + h2 + 360) / 2
+		default:
+			hbar = (h1 + h2 - 360) / 2
+		}
+	}
+	dH := 2 * math.Sqrt(c1*c2) * math.Sin(dh/2*rad)
+
+	lbar, cbar := (l1+l2)/2, (c1+c2)/2
+	t := 1 - 0.17*math.Cos((hbar-30)*rad) + 0.24*math.Cos(2*hbar*rad) + 0.32*math.Cos((3*hbar+6)*rad) -
+		0.20*math.Cos((4*hbar-63)*rad)
+	dtheta := 30 * math.Exp(-((hbar-275)/25)*((hbar-275)/25))
+	cbar7 = math.Pow(cbar, 7)
+	rc := 2 * math.Sqrt(cbar7/(cbar7+pow25to7))
+	sl := 1 + 0.015*(lbar-50)*(lbar-50)/math.Sqrt(20+(lbar-50)*(lbar-50))
+	sc := 1 + 0.045*cbar
+	sh := 1 + 0.015*cbar*t
+	rt := -math.Sin(2*dtheta*rad) * rc
+	return math.Sqrt((dl/sl)*(dl/sl) + (dc/sc)*(dc/sc) + (dH/sh)*(dH/sh) + rt*(dc/sc)*(dH/sh))
+}
+
+// opaqueEntries returns the opaque entries of p and their indices, since metrics compare colors without alpha
+func opaqueEntries(p color.Palette) ([]color.RGBA, []int) {
+	var entries []color.RGBA
+	var indices []int
+	for i, c := range p {
+		if rgba := color.RGBAModel.Convert(c).(color.RGBA); rgba.A == 255 {
+			entries = append(entries, rgba)
+			indices = append(indices, i)
+		}
+	}
+	return entries, indices
+}
+
+// nearestBy returns the position of the entry nearest to c under metric, preferring the lowest position among
+// equals, or -1 if there are no entries
+func nearestBy(entries []color.RGBA, c color.RGBA, metric Distance) int {
+	best, bestDist := -1, math.Inf(1)
+	for i, e := range entries {
+		if d := metric.Distance(c, e); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,104 @@
+package quantize
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestErrorMap(t *testing.T) {
+	orig := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	orig.SetRGBA(0, 0, color.RGBA{0, 0, 0, 255})
+	orig.SetRGBA(1, 0, color.RGBA{3, 4, 0, 255})
+	orig.SetRGBA(2, 0, color.RGBA{255, 255, 255, 255})
+	quantized := image.NewPaletted(orig.Bounds(), color.Palette{color.RGBA{0, 0, 0, 255}})
+	m := ErrorMap(orig, quantized, nil)
+	if m.Pix[0] != 0 || m.Pix[1] != 5 || m.Pix[2] != 255 {
+		t.Fatalf("Expected errors 0, 5 and 255, got %v", m.Pix)
+	}
+
+	half := DistanceFunc(func(a, b color.RGBA) float64 { return EuclideanRGB{}.Distance(a, b) / 2 })
+	if m := ErrorMap(orig, quantized, half); m.Pix[1] != 3 {
+		t.Fatalf("Expected a custom metric to be used, got %v", m.Pix)
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 16), i)
+	pm := image.NewPaletted(i.Bounds(), p)
+	draw.Draw(pm, pm.Bounds(), i, i.Bounds().Min, draw.Src)
+	var total float64
+	for _, v := range ErrorMap(i, pm, nil).Pix {
+		total += float64(v)
+	}
+	if total == 0 {
+		t.Fatal("Expected a 16 color image to have some error")
+	}
+}
+
+func TestCIEDE2000(t *testing.T) {
+	// Pairs from Sharma, Wu and Dalal's CIEDE2000 test data
+	for _, tc := range [][7]float64{
+		{50, 2.6772, -79.7751, 50, 0, -82.7485, 2.0425},
+		{50, 0, 0, 50, -1, 2, 2.3669},
+		{50, 2.49, -0.001, 50, -2.49, 0.0009, 7.1792},
+		{50, 2.5, 0, 73, 25, -18, 27.1492},
+		{2.0776, 0.0795, -1.1350, 0.9033, -0.0636, -0.5514, 0.9082},
+	} {
+		if d := ciede2000(tc[0], tc[1], tc[2], tc[3], tc[4], tc[5]); math.Abs(d-tc[6]) > 1e-4 {
+			t.Fatalf("Expected a difference of %f for %v, got %f", tc[6], tc[:6], d)
+		}
+		if d := ciede2000(tc[3], tc[4], tc[5], tc[0], tc[1], tc[2]); math.Abs(d-tc[6]) > 1e-4 {
+			t.Fatalf("Expected the difference for %v to be symmetric, got %f", tc[:6], d)
+		}
+	}
+}
+
+func TestDistanceMetrics(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+	for _, metric := range []Distance{EuclideanRGB{}, Redmean{}, CIE76{}, CIEDE2000{}, OKLabDistance{}} {
+		if d := metric.Distance(white, white); d != 0 {
+			t.Fatalf("Expected %T to give equal colors no distance, got %f", metric, d)
+		}
+		near := metric.Distance(color.RGBA{100, 100, 100, 255}, color.RGBA{104, 100, 100, 255})
+		if far := metric.Distance(black, white); far <= near {
+			t.Fatalf("Expected %T to put black and white further apart than similar grays", metric)
+		}
+	}
+	if d := (CIE76{}).Distance(black, white); math.Abs(d-100) > 0.01 {
+		t.Fatalf("Expected black and white 100 apart in CIELAB, got %f", d)
+	}
+}
+
+func TestRemapDistance(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{AddTransparent: true}.Quantize(make(color.Palette, 0, 8), m)
+	if got, want := RemapDistance(m, p, EuclideanRGB{}), Remap(m, p); !bytes.Equal(got.Pix, want.Pix) {
+		t.Fatal("Expected Euclidean remapping to match Remap")
+	}
+	entries := make([]color.RGBA, len(p))
+	for i, c := range p {
+		entries[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	q := MedianCutQuantizer{AddTransparent: true, Distance: CIEDE2000{}}
+	for _, out := range []*image.Paletted{RemapDistance(m, p, CIEDE2000{}), q.Paletted(m, 8, nil)} {
+		b := out.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y += 7 {
+			for x := b.Min.X; x < b.Max.X; x += 7 {
+				c := rgbaAt(m, x, y)
+				index := out.ColorIndexAt(x, y)
+				if entries[index].A != 255 {
+					t.Fatalf("Expected opaque pixels to map to opaque entries, got %d", index)
+				}
+				got := (CIEDE2000{}).Distance(c, out.Palette[index].(color.RGBA))
+				for _, e := range out.Palette {
+					if e := e.(color.RGBA); e.A == 255 && (CIEDE2000{}).Distance(c, e) < got-1e-9 {
+						t.Fatalf("Expected %v to map to its nearest entry under CIEDE2000", c)
+					}
+				}
+			}
+		}
+	}
+}
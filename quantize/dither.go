@@ -0,0 +1,201 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// FloydSteinberg implements the go draw.Drawer interface with Floyd-Steinberg error diffusion onto paletted images.
+// Destinations other than *image.Paletted are drawn without dithering.
+type FloydSteinberg struct {
+	// How strongly dithering is suppressed near edges. 0 dithers uniformly, while larger values keep text and line
+	// art crisp by diffusing less error into pixels with a large local gradient.
+	EdgeFalloff float64
+	// The fraction of each pixel's error diffused into its neighbors, from 0 to 1. Values below 1 trade accuracy of
+	// the average color for less visible noise. If zero, all of the error is diffused
+	Strength float64
+	// Whether alternate rows are scanned right to left, which breaks up the diagonal artifacts of scanning every row
+	// in the same direction
+	Serpentine bool
+	// Whether error is accumulated in linear light rather than between gamma-encoded sRGB values, which keeps
+	// dithered midtones from darkening at small palette sizes
+	Linear bool
+}
+
+// clipDraw clips r to the destination and source bounds, adjusting sp to match
+func clipDraw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) (image.Rectangle, image.Point) {
+	orig := r.Min
+	r = r.Intersect(dst.Bounds())
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	return r, sp.Add(r.Min.Sub(orig))
+}
+
+// luma returns the 8-bit luma of a pixel
+func luma(m image.Image, x int, y int) int {
+	c := rgbaAt(m, x, y)
+	return (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+}
+
+// gradient returns the largest luma difference between a pixel and its direct neighbors
+func gradient(m image.Image, x int, y int) int {
+	b := m.Bounds()
+	center := luma(m, x, y)
+	g := 0
+	for _, n := range [4]image.Point{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+		if !n.In(b) {
+			continue
+		}
+		d := luma(m, n.X, n.Y) - center
+		if d < 0 {
+			d = -d
+		}
+		if d > g {
+			g = d
+		}
+	}
+	return g
+}
+
+// clamp16 limits a value to the range of a 16-bit color channel
+func clamp16(v int32) int32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 0xffff {
+		return 0xffff
+	}
+	return v
+}
+
+// nearest returns the index of the palette entry closest to a 16-bit color
+func nearest(palette [][4]int32, c [4]int32) int {
+	best, bestDist := 0, int64(-1)
+	for i, e := range palette {
+		var dist int64
+		for ch := range c {
+			d := int64(c[ch]-e[ch]) >> 1
+			dist += d * d
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// entries16 converts a palette to 16-bit premultiplied channels
+func entries16(p color.Palette) [][4]int32 {
+	entries := make([][4]int32, len(p))
+	for i, c := range p {
+		cr, cg, cb, ca := c.RGBA()
+		entries[i] = [4]int32{int32(cr), int32(cg), int32(cb), int32(ca)}
+	}
+	return entries
+}
+
+// diffusion returns the equivalent ErrorDiffusion drawer
+func (d FloydSteinberg) diffusion() ErrorDiffusion {
+	return ErrorDiffusion{
+		Kernel:      FloydSteinbergKernel,
+		EdgeFalloff: d.EdgeFalloff,
+		Strength:    d.Strength,
+		Serpentine:  d.Serpentine,
+		Linear:      d.Linear,
+	}
+}
+
+// diffuse runs error diffusion with the Floyd-Steinberg kernel, as described by ErrorDiffusion.diffuse
+func (d FloydSteinberg) diffuse(r image.Rectangle, src image.Image, sp image.Point, pick func(x, y int, c [4]int32) [4]int32) {
+	d.diffusion().diffuse(r, src, sp, pick)
+}
+
+// Draw dithers the part of src starting at sp onto the rectangle r of dst
+func (d FloydSteinberg) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	d.diffusion().Draw(dst, r, src, sp)
+}
+
+// Ordered implements the go draw.Drawer interface with ordered dithering using a Bayer threshold matrix. Unlike
+// error diffusion each pixel is mapped independently, so the output is stable between animation frames.
+// Destinations other than *image.Paletted are drawn without dithering.
+type Ordered struct {
+	// The side of the threshold matrix, rounded up to a power of two. If zero or negative, 4 is used
+	Size int
+	// The peak-to-peak amplitude of the threshold in 8-bit channel units. If zero, the spacing between levels of a
+	// uniform palette the size of the destination palette is used
+	Spread float64
+	// Shifts of the threshold matrix applied to the red, green and blue channels. Distinct offsets decorrelate the
+	// quantization noise of each channel, which reduces color banding at small palette sizes
+	ChannelOffsets [3]image.Point
+}
+
+// bayer returns the n×n Bayer matrix, holding each value from 0 to n*n-1 once
+func bayer(n int) [][]int {
+	m := [][]int{{0}}
+	for size := 1; size < n; size *= 2 {
+		next := make([][]int, size*2)
+		for y := range next {
+			next[y] = make([]int, size*2)
+			for x := range next[y] {
+				v := m[y%size][x%size] * 4
+				switch {
+				case y < size && x >= size:
+					v += 2
+				case y >= size && x < size:
+					v += 3
+				case y >= size && x >= size:
+					v++
+				}
+				next[y][x] = v
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+// Draw dithers the part of src starting at sp onto the rectangle r of dst
+func (d Ordered) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	p, ok := dst.(*image.Paletted)
+	if !ok || len(p.Palette) == 0 {
+		draw.Draw(dst, r, src, sp, draw.Src)
+		return
+	}
+	r, sp = clipDraw(dst, r, src, sp)
+	if r.Empty() {
+		return
+	}
+	palette := entries16(p.Palette)
+	size := 4
+	if d.Size > 0 {
+		for size = 1; size < d.Size; size *= 2 {
+		}
+	}
+	spread := d.Spread
+	if spread == 0 {
+		spread = 255 / math.Max(1, math.Cbrt(float64(len(p.Palette)))-1)
+	}
+	matrix := bayer(size)
+	thresholds := make([][]int32, size)
+	for y := range thresholds {
+		thresholds[y] = make([]int32, size)
+		for x := range thresholds[y] {
+			t := (float64(matrix[y][x])+0.5)/float64(size*size) - 0.5
+			thresholds[y][x] = int32(t * spread * 0x101)
+		}
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx, sy := sp.X+x-r.Min.X, sp.Y+y-r.Min.Y
+			cr, cg, cb, ca := src.At(sx, sy).RGBA()
+			c := [4]int32{int32(cr), int32(cg), int32(cb), int32(ca)}
+			for ch, off := range d.ChannelOffsets {
+				tx, ty := (x+off.X)&(size-1), (y+off.Y)&(size-1)
+				c[ch] = clamp16(c[ch] + thresholds[ty][tx])
+			}
+			p.SetColorIndex(x, y, uint8(nearest(palette, c)))
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var blackWhite = color.Palette{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}}
+
+func meanGray(m *image.Paletted) float64 {
+	var sum float64
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sum += float64(rgbaAt(m, x, y).R)
+		}
+	}
+	return sum / float64(b.Dx()*b.Dy())
+}
+
+func TestFloydSteinberg(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	FloydSteinberg{}.Draw(dst, dst.Bounds(), src, image.Point{})
+	if mean := meanGray(dst); mean < 56 || mean > 72 {
+		t.Fatalf("Expected dithered mean near 64, got %f", mean)
+	}
+}
+
+func TestFloydSteinbergEdgeFalloff(t *testing.T) {
+	// Every pixel of a fine checkerboard sits on an edge, so strong falloff disables dithering entirely
+	src := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(100)
+			if (x+y)%2 == 1 {
+				v = 160
+			}
+			src.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	dst := image.NewPaletted(src.Bounds(), blackWhite)
+	FloydSteinberg{EdgeFalloff: 4}.Draw(dst, dst.Bounds(), src, image.Point{})
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if want := uint8((x + y) % 2); dst.ColorIndexAt(x, y) != want {
+				t.Fatalf("Expected undithered index %d at (%d, %d), got %d", want, x, y, dst.ColorIndexAt(x, y))
+			}
+		}
+	}
+}
+
+func TestBayer(t *testing.T) {
+	m := bayer(8)
+	seen := make(map[int]bool)
+	for _, row := range m {
+		for _, v := range row {
+			seen[v] = true
+		}
+	}
+	if len(m) != 8 || len(seen) != 64 || !seen[0] || !seen[63] {
+		t.Fatalf("Expected each value 0-63 once, got %v", m)
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	Ordered{}.Draw(dst, dst.Bounds(), src, image.Point{})
+	if mean := meanGray(dst); mean < 56 || mean > 72 {
+		t.Fatalf("Expected dithered mean near 64, got %f", mean)
+	}
+	// Invalid sizes fall back to the default, and other sizes round up to a power of two
+	for _, size := range []int{-1, 3} {
+		other := image.NewPaletted(dst.Bounds(), blackWhite)
+		Ordered{Size: size}.Draw(other, other.Bounds(), src, image.Point{})
+		if string(other.Pix) != string(dst.Pix) {
+			t.Fatalf("Expected size %d to draw like size 4", size)
+		}
+	}
+}
+
+func TestOrderedChannelOffsets(t *testing.T) {
+	// With shared thresholds a gray input only ever maps to gray entries, while decorrelated channels mix hues
+	p := color.Palette{}
+	for _, r := range []uint8{0, 255} {
+		for _, g := range []uint8{0, 255} {
+			for _, b := range []uint8{0, 255} {
+				p = append(p, color.RGBA{r, g, b, 255})
+			}
+		}
+	}
+	src := image.NewUniform(color.RGBA{128, 128, 128, 255})
+	chromatic := func(d Ordered) int {
+		dst := image.NewPaletted(image.Rect(0, 0, 16, 16), p)
+		d.Draw(dst, dst.Bounds(), src, image.Point{})
+		n := 0
+		for _, i := range dst.Pix {
+			if i != 0 && i != 7 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := chromatic(Ordered{}); n != 0 {
+		t.Fatalf("Expected only black and white with shared thresholds, got %d colored pixels", n)
+	}
+	d := Ordered{ChannelOffsets: [3]image.Point{{0, 0}, {1, 0}, {0, 1}}}
+	if n := chromatic(d); n == 0 {
+		t.Fatal("Expected decorrelated channels to produce colored pixels")
+	}
+}
+
+func TestFloydSteinbergStrength(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	full := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	FloydSteinberg{}.Draw(full, full.Bounds(), src, image.Point{})
+	weak := image.NewPaletted(full.Rect, blackWhite)
+	FloydSteinberg{Strength: 0.5}.Draw(weak, weak.Bounds(), src, image.Point{})
+	// Half of each error reaches the neighbors, so fewer pixels cross over to white
+	if meanGray(weak) >= meanGray(full) {
+		t.Fatalf("Expected reduced strength to lighten fewer pixels, got means %f and %f", meanGray(weak), meanGray(full))
+	}
+}
+
+func TestFloydSteinbergSerpentine(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	dst := image.NewPaletted(image.Rect(0, 0, 64, 64), blackWhite)
+	FloydSteinberg{Serpentine: true}.Draw(dst, dst.Bounds(), src, image.Point{})
+	if mean := meanGray(dst); mean < 56 || mean > 72 {
+		t.Fatalf("Expected dithered mean near 64, got %f", mean)
+	}
+	plain := image.NewPaletted(dst.Rect, blackWhite)
+	FloydSteinberg{}.Draw(plain, plain.Bounds(), src, image.Point{})
+	if string(plain.Pix) == string(dst.Pix) {
+		t.Fatal("Expected serpentine scanning to change the dither pattern")
+	}
+}
@@ -0,0 +1,41 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// FixedQuantizer implements the go draw.Quantizer interface by skipping palette generation and returning a
+// caller-supplied palette, such as the web-safe, xterm or a hardware palette, for images that must be remapped onto
+// it.
+type FixedQuantizer struct {
+	// The palette every image is mapped onto
+	Palette color.Palette
+	// The drawer used by Paletted. If nil each pixel is mapped to its nearest entry
+	Drawer draw.Drawer
+	// The metric Paletted uses to find the nearest entry when Drawer is nil. If nil, Euclidean RGB distance is used
+	Distance Distance
+}
+
+// Quantize appends the fixed palette to p, ignoring the image and the capacity of p
+func (q FixedQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	return append(p, q.Palette...)
+}
+
+// Paletted returns m mapped onto the fixed palette. Without a drawer or metric the image is remapped in parallel
+// with a PaletteIndexer.
+func (q FixedQuantizer) Paletted(m image.Image) *image.Paletted {
+	if q.Drawer == nil {
+		if q.Distance != nil {
+			return RemapDistance(m, q.Palette, q.Distance)
+		}
+		return RemapParallel(m, q.Palette, nil)
+	}
+	b := m.Bounds()
+	out := image.NewPaletted(b, q.Palette)
+	if len(q.Palette) > 0 {
+		q.Drawer.Draw(out, b, m, b.Min)
+	}
+	return out
+}
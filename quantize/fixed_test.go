@@ -0,0 +1,44 @@
+package quantize
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+)
+
+func TestFixedQuantizer(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg")
+	p := UniformQuantizer{}.Quantize(make(color.Palette, 0, 256), m)
+	q := FixedQuantizer{Palette: p}
+	if got := q.Quantize(make(color.Palette, 0, 256), m); len(got) != len(p) || got[100] != p[100] {
+		t.Fatalf("Expected the fixed palette, got %d colors", len(got))
+	}
+
+	out := q.Paletted(m)
+	if want := Remap(m, p); !bytes.Equal(out.Pix, want.Pix) {
+		t.Fatal("Expected Paletted to match Remap without a drawer")
+	}
+	perceptual := FixedQuantizer{Palette: p, Distance: Redmean{}}
+	if want := RemapDistance(m, p, Redmean{}); !bytes.Equal(perceptual.Paletted(m).Pix, want.Pix) {
+		t.Fatal("Expected Paletted to remap with the metric")
+	}
+
+	flat := image.NewGray(image.Rect(0, 0, 32, 32))
+	draw.Draw(flat, flat.Rect, image.NewUniform(color.Gray{100}), image.Point{}, draw.Src)
+	bw := FixedQuantizer{Palette: color.Palette{color.Black, color.White}, Drawer: FloydSteinberg{}}
+	var white int
+	for _, v := range bw.Paletted(flat).Pix {
+		white += int(v)
+	}
+	if white < 32*32*100/255-40 || white > 32*32*100/255+40 {
+		t.Fatalf("Expected dithering to preserve the gray level, got %d white pixels", white)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, m, &gif.Options{NumColors: 256, Quantizer: q, Drawer: draw.Src}); err != nil {
+		t.Fatal(err)
+	}
+}
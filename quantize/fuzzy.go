@@ -0,0 +1,109 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FuzzyQuantizer implements the go draw.Quantizer interface using fuzzy c-means clustering of the image histogram.
+// Each color contributes to every center in proportion to its membership rather than only to the nearest one,
+// which gives smoother palettes for images with many near-duplicate hues.
+type FuzzyQuantizer struct {
+	// Histogram, transparency and median cut seeding options
+	MedianCut MedianCutQuantizer
+	// How soft memberships are, greater than 1. Values near 1 approach k-means. If zero, 2 is used
+	Fuzziness float64
+	// When to stop refining
+	Convergence
+}
+
+// fuzzyCMeans refines centers by fuzzy c-means with fuzziness m
+func fuzzyCMeans(colors colorBucket, centers []center, m float64, conv Convergence) []center {
+	if len(colors) == 0 || len(centers) == 0 {
+		return centers
+	}
+	exp := 1 / (m - 1)
+	sums := make([]center, len(centers))
+	weights := make([]float64, len(centers))
+	members := make([]float64, len(centers))
+	for iter := 0; iter < conv.iterations(); iter++ {
+		for i := range sums {
+			sums[i] = center{}
+			weights[i] = 0
+		}
+		var errSum, total float64
+		for _, c := range colors {
+			// Membership in each center is proportional to its squared distance raised to -1/(m-1)
+			exact := -1
+			var norm float64
+			nearest := math.Inf(1)
+			for i, ctr := range centers {
+				d := ctr.dist(c.RGBA)
+				nearest = math.Min(nearest, d)
+				if d == 0 {
+					exact = i
+					break
+				}
+				members[i] = math.Pow(d, -exp)
+				norm += members[i]
+			}
+			w := float64(c.p)
+			errSum += nearest * w
+			total += w
+			for i := range centers {
+				u := 0.0
+				if exact >= 0 {
+					if i == exact {
+						u = 1
+					}
+				} else {
+					u = members[i] / norm
+				}
+				uw := math.Pow(u, m) * w
+				sums[i][0] += float64(c.R) * uw
+				sums[i][1] += float64(c.G) * uw
+				sums[i][2] += float64(c.B) * uw
+				weights[i] += uw
+			}
+		}
+		var moved float64
+		for i := range centers {
+			if weights[i] == 0 {
+				continue
+			}
+			next := center{sums[i][0] / weights[i], sums[i][1] / weights[i], sums[i][2] / weights[i]}
+			moved = math.Max(moved, math.Sqrt(next.distTo(centers[i])))
+			centers[i] = next
+		}
+		if conv.Progress != nil && !conv.Progress(iter, errSum/total) {
+			break
+		}
+		if moved <= conv.Epsilon {
+			break
+		}
+	}
+	return centers
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q FuzzyQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	numColors, addTransparent := q.MedianCut.target(p)
+	var centers []center
+	for _, c := range q.MedianCut.palettize(nil, q.MedianCut.bucketize(bucket, numColors)) {
+		centers = append(centers, centerOf(c.(color.RGBA)))
+	}
+	fuzziness := q.Fuzziness
+	if fuzziness <= 1 {
+		fuzziness = 2
+	}
+	for _, c := range fuzzyCMeans(bucket, centers, fuzziness, q.Convergence) {
+		p = append(p, c.rgba())
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,30 @@
+package quantize
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestFuzzyQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	var errs []float64
+	q := FuzzyQuantizer{Convergence: Convergence{MaxIterations: 5, Progress: func(iteration int, meanError float64) bool {
+		errs = append(errs, meanError)
+		return true
+	}}}
+	p := q.Quantize(make(color.Palette, 0, 16), i)
+	if len(p) != 16 {
+		t.Fatalf("Expected 16 colors, got %d", len(p))
+	}
+	if len(errs) == 0 {
+		t.Fatal("Expected progress to be reported")
+	}
+	t.Logf("Fuzzy c-means error %f, errs during refinement %v", Quality(p, i).MeanError, errs)
+
+	// Two well separated colors are found exactly regardless of fuzziness
+	colors := colorBucket{{10, color.RGBA{0, 0, 0, 255}}, {10, color.RGBA{255, 255, 255, 255}}}
+	centers := fuzzyCMeans(colors, []center{{50, 50, 50}, {200, 200, 200}}, 1.5, Convergence{MaxIterations: 50})
+	if centers[0].rgba() != colors[0].RGBA || centers[1].rgba() != colors[1].RGBA {
+		t.Fatalf("Expected centers on both colors, got %v", centers)
+	}
+}
@@ -0,0 +1,428 @@
+package quantize
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"math"
+	"sort"
+)
+
+// GIFPlan describes which frames of an animation use the global color table and which carry local tables
+type GIFPlan struct {
+	// The palette shared by all frames without a local table
+	Global color.Palette
+	// The local palette for each frame, or nil if the frame uses the global palette
+	Local []color.Palette
+}
+
+// tableSize returns the number of bytes a GIF color table holding n colors occupies
+func tableSize(n int) int {
+	size := 2
+	for size < n {
+		size *= 2
+	}
+	return size * 3
+}
+
+// PlanGIF builds a global palette for the frames of an animation, then assigns local palettes to the frames that
+// gain the most from them while spending at most budget bytes on local color tables
+func (q MedianCutQuantizer) PlanGIF(frames []image.Image, numColors int, budget int) GIFPlan {
+	plan := GIFPlan{
+		Global: q.QuantizeMultiple(make(color.Palette, 0, numColors), frames),
+		Local:  make([]color.Palette, len(frames)),
+	}
+	type candidate struct {
+		frame   int
+		palette color.Palette
+		gain    float64
+		cost    int
+	}
+	candidates := make([]candidate, 0, len(frames))
+	for i, m := range frames {
+		local := q.Quantize(make(color.Palette, 0, numColors), m)
+		global := Quality(plan.Global, m)
+		gain := (global.MeanError - Quality(local, m).MeanError) * float64(global.Pixels)
+		if gain > 0 {
+			candidates = append(candidates, candidate{i, local, gain, tableSize(len(local))})
+		}
+	}
+	// Greedily choose the frames with the best error reduction per byte
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].gain/float64(candidates[i].cost) > candidates[j].gain/float64(candidates[j].cost)
+	})
+	for _, c := range candidates {
+		if c.cost <= budget {
+			plan.Local[c.frame] = c.palette
+			budget -= c.cost
+		}
+	}
+	return plan
+}
+
+// gifTransparent returns the index of the entry a GIF encoder treats as transparent, or -1 if there is none
+func gifTransparent(p color.Palette) int {
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// pixelAt returns the color of a pixel on a canvas, where a nil canvas is fully transparent
+func pixelAt(m image.Image, x int, y int) color.RGBA {
+	if m == nil {
+		return color.RGBA{}
+	}
+	return rgbaAt(m, x, y)
+}
+
+// FrameOptions controls how PrepareFrames assembles an animation
+type FrameOptions struct {
+	// The drawer used to remap frames onto their palettes. If nil, draw.FloydSteinberg is used
+	Drawer draw.Drawer
+	// The largest RGB distance at which a pixel is treated as unchanged from the canvas beneath it, so that it can
+	// be left transparent. Larger values shift colors slightly in exchange for smaller files; zero is lossless
+	Lossiness float64
+}
+
+// unchanged reports whether a pixel may be left showing the canvas beneath it
+func (o FrameOptions) unchanged(pixel, canvas color.RGBA) bool {
+	if pixel == canvas {
+		return true
+	}
+	if o.Lossiness <= 0 || pixel.A != 255 || canvas.A != 255 {
+		return false
+	}
+	return float64(sqDiff(pixel, canvas)) <= o.Lossiness*o.Lossiness
+}
+
+// frameDiff returns the bounds of the pixels in r that change between two canvases and how many there are
+func (o FrameOptions) frameDiff(a, b image.Image, r image.Rectangle) (changed image.Rectangle, count int) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if !o.unchanged(pixelAt(a, x, y), pixelAt(b, x, y)) {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+				count++
+			}
+		}
+	}
+	return
+}
+
+// composite returns the canvas shown after drawing m over base, where unchanged pixels keep their base color
+func (o FrameOptions) composite(m, base image.Image, r image.Rectangle) image.Image {
+	if base == nil || o.Lossiness <= 0 {
+		return m
+	}
+	shown := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c, b := pixelAt(m, x, y), pixelAt(base, x, y)
+			if o.unchanged(c, b) {
+				c = b
+			}
+			shown.SetRGBA(x, y, c)
+		}
+	}
+	return shown
+}
+
+// uncovers reports whether next has transparent pixels where canvas is opaque, which can't be drawn over canvas
+func uncovers(next, canvas image.Image, r image.Rectangle) bool {
+	if canvas == nil {
+		return false
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if pixelAt(next, x, y).A == 0 && pixelAt(canvas, x, y).A != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrepareFrames remaps the frames of an animation onto the palettes in plan and assembles them into a GIF. Each
+// frame's disposal method is chosen by comparing the following frame against the canvas each method would leave,
+// and frames are cropped to the region that changes, with unchanged pixels set to the transparent entry when the
+// palette has one.
+func PrepareFrames(frames []image.Image, delays []int, plan GIFPlan, opts FrameOptions) *gif.GIF {
+	d := opts.Drawer
+	if d == nil {
+		d = draw.FloydSteinberg
+	}
+	g := &gif.GIF{
+		Image:    make([]*image.Paletted, len(frames)),
+		Delay:    make([]int, len(frames)),
+		Disposal: make([]byte, len(frames)),
+	}
+	copy(g.Delay, delays)
+	if len(frames) == 0 {
+		return g
+	}
+	bounds := frames[0].Bounds()
+	g.Config = image.Config{ColorModel: plan.Global, Width: bounds.Dx(), Height: bounds.Dy()}
+
+	var base image.Image // The canvas the current frame is drawn over, nil when blank
+	for i, m := range frames {
+		p := plan.Global
+		if i < len(plan.Local) && plan.Local[i] != nil {
+			p = plan.Local[i]
+		}
+		shown := opts.composite(m, base, bounds)
+
+		// Pick the disposal leaving the canvas closest to the next frame, in order of preference
+		disposal := byte(gif.DisposalNone)
+		after := shown
+		if i+1 < len(frames) {
+			next := frames[i+1]
+			best := -1
+			for _, option := range []struct {
+				disposal byte
+				canvas   image.Image
+			}{{gif.DisposalNone, shown}, {gif.DisposalPrevious, base}, {gif.DisposalBackground, nil}} {
+				if uncovers(next, option.canvas, bounds) {
+					continue
+				}
+				if _, diff := opts.frameDiff(next, option.canvas, bounds); best < 0 || diff < best {
+					best, disposal, after = diff, option.disposal, option.canvas
+				}
+			}
+		}
+
+		// Crop to the changed region unless the whole canvas will be cleared afterwards
+		r, _ := opts.frameDiff(m, base, bounds)
+		if base == nil || disposal == gif.DisposalBackground {
+			r = bounds
+		} else if r.Empty() {
+			r = image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+1, bounds.Min.Y+1)
+		}
+		out := image.NewPaletted(r.Sub(bounds.Min), p)
+		d.Draw(out, out.Bounds(), m, r.Min)
+		if t := gifTransparent(p); base != nil && t >= 0 {
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					if opts.unchanged(pixelAt(m, x, y), pixelAt(base, x, y)) {
+						out.SetColorIndex(x-bounds.Min.X, y-bounds.Min.Y, uint8(t))
+					}
+				}
+			}
+		}
+		g.Image[i] = out
+		g.Disposal[i] = disposal
+		base = after
+	}
+	return g
+}
+
+// MergeDuplicateFrames drops frames that are identical to the frame before them, within an RGB distance of
+// tolerance per pixel, adding their delays to the frame that is kept. Merging before quantization keeps repeated
+// frames from skewing the histogram and shrinks the output.
+func MergeDuplicateFrames(frames []image.Image, delays []int, tolerance float64) ([]image.Image, []int) {
+	opts := FrameOptions{Lossiness: tolerance}
+	merged := make([]image.Image, 0, len(frames))
+	mergedDelays := make([]int, 0, len(frames))
+	for i, m := range frames {
+		var delay int
+		if i < len(delays) {
+			delay = delays[i]
+		}
+		if last := len(merged) - 1; last >= 0 && m.Bounds() == merged[last].Bounds() {
+			if _, diff := opts.frameDiff(m, merged[last], m.Bounds()); diff == 0 {
+				mergedDelays[last] += delay
+				continue
+			}
+		}
+		merged = append(merged, m)
+		mergedDelays = append(mergedDelays, delay)
+	}
+	return merged, mergedDelays
+}
+
+// GIFOptions controls how QuantizeGIF remaps an animation
+type GIFOptions struct {
+	// The drawer used to remap frames onto their palettes. If nil, FloydSteinberg is used
+	Drawer draw.Drawer
+	// Whether each frame is quantized to its own local palette instead of a global palette shared by all frames,
+	// which looks much better across heavy scene changes at the cost of a color table per frame
+	LocalPalettes bool
+	// With LocalPalettes, whether each frame's palette is refined from the previous frame's rather than built from
+	// scratch, keeping colors stable between similar frames
+	SeedFromPrevious bool
+	// With LocalPalettes and without SeedFromPrevious, the fraction, below 1, of each frame's histogram carried into
+	// the next with decaying weight. Palettes then drift gradually instead of shifting slightly on every frame, which
+	// shows as shimmering
+	Stability float64
+	// Whether pixels that wouldn't change the canvas shown before their frame, given the disposal methods of the
+	// frames before it, are set to the transparent entry, which compresses far better. A transparent entry is reserved
+	// in every palette
+	InterFrameTransparency bool
+}
+
+// clearUnchanged replaces each pixel of g that would redraw the color already shown at its position with the
+// transparent index of its frame, replaying the animation's disposal methods to track the canvas
+func clearUnchanged(g *gif.GIF) {
+	var bounds image.Rectangle
+	for _, frame := range g.Image {
+		bounds = bounds.Union(frame.Rect)
+	}
+	canvas := image.NewRGBA(bounds)
+	for i, frame := range g.Image {
+		var disposal byte
+		if g.Disposal != nil {
+			disposal = g.Disposal[i]
+		}
+		var saved []uint8
+		if disposal == gif.DisposalPrevious {
+			saved = append(saved, canvas.Pix...)
+		}
+		t := gifTransparent(frame.Palette)
+		entries := make([]color.RGBA, len(frame.Palette))
+		for j, c := range frame.Palette {
+			entries[j] = color.RGBAModel.Convert(c).(color.RGBA)
+		}
+		for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+			for x := frame.Rect.Min.X; x < frame.Rect.Max.X; x++ {
+				o := frame.PixOffset(x, y)
+				c := entries[frame.Pix[o]]
+				if c.A == 0 {
+					continue // Transparent pixels leave the canvas as it is
+				}
+				if t >= 0 && c.A == 255 && canvas.RGBAAt(x, y) == c {
+					frame.Pix[o] = uint8(t)
+					continue
+				}
+				canvas.SetRGBA(x, y, c)
+			}
+		}
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Rect, image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			copy(canvas.Pix, saved)
+		}
+	}
+}
+
+// decayed appends the colors of bucket to dst with their priority scaled by factor, dropping those that reach zero.
+// Priorities are truncated so that every one shrinks, and a color no longer seen eventually drops out
+func decayed(dst, bucket colorBucket, factor float64) colorBucket {
+	for _, c := range bucket {
+		if p := uint32(float64(c.p) * factor); p != 0 {
+			dst = append(dst, colorPriority{p, c.RGBA})
+		}
+	}
+	return dst
+}
+
+// QuantizeGIF quantizes the frames of g to palettes of at most numColors colors, including any transparent entry,
+// and returns a copy of the animation remapped onto them. Frames share a single global palette unless
+// opts.LocalPalettes is set. Frame bounds, delays, disposal methods and the loop count are preserved. A transparent
+// entry is added when any frame's palette has one, and the fully transparent pixels of every frame are mapped to it.
+func (q MedianCutQuantizer) QuantizeGIF(g *gif.GIF, numColors int, opts GIFOptions) (*gif.GIF, error) {
+	if len(g.Image) == 0 {
+		return nil, errors.New("quantize: animation has no frames")
+	}
+	if len(g.Delay) != len(g.Image) {
+		return nil, fmt.Errorf("quantize: %d delays for %d frames", len(g.Delay), len(g.Image))
+	}
+	if g.Disposal != nil && len(g.Disposal) != len(g.Image) {
+		return nil, fmt.Errorf("quantize: %d disposal methods for %d frames", len(g.Disposal), len(g.Image))
+	}
+	d := opts.Drawer
+	if d == nil {
+		d = FloydSteinberg{}
+	}
+	frames := make([]image.Image, len(g.Image))
+	for i, m := range g.Image {
+		frames[i] = m
+		if gifTransparent(m.Palette) >= 0 {
+			q.AddTransparent = true
+		}
+	}
+	if opts.InterFrameTransparency {
+		q.AddTransparent = true
+	}
+	if q.AddTransparent && q.AlphaThreshold == 0 {
+		q.AlphaThreshold = 1 // Transparent pixels map to the transparent entry rather than the histogram
+	}
+	palettes := make([]color.Palette, len(frames))
+	switch {
+	case opts.LocalPalettes && opts.SeedFromPrevious:
+		for i, m := range frames {
+			if i > 0 && len(palettes[i-1]) > 0 {
+				palettes[i] = q.Refine(palettes[i-1], m)
+			} else {
+				palettes[i] = q.Quantize(make(color.Palette, 0, numColors), m)
+			}
+		}
+	case opts.LocalPalettes && opts.Stability > 0:
+		q.Scratch = nil // Several histograms are held at once
+		factor := math.Min(opts.Stability, 0.99)
+		var carried colorBucket
+		for i, m := range frames {
+			bucket := q.buildBucket(m)
+			if carried != nil {
+				merged := q.mergeBuckets([]colorBucket{bucket, carried})
+				q.putBucket(bucket)
+				bucket = merged
+			}
+			carried = decayed(carried[:0], bucket, factor)
+			palettes[i] = q.quantizeSlice(make(color.Palette, 0, numColors), bucket)
+			q.putBucket(bucket)
+		}
+	case opts.LocalPalettes:
+		for i, m := range frames {
+			palettes[i] = q.Quantize(make(color.Palette, 0, numColors), m)
+		}
+	default:
+		global := q.QuantizeMultiple(make(color.Palette, 0, numColors), frames)
+		for i := range palettes {
+			palettes[i] = global
+		}
+	}
+
+	out := &gif.GIF{
+		Image:     make([]*image.Paletted, len(g.Image)),
+		Delay:     append([]int(nil), g.Delay...),
+		LoopCount: g.LoopCount,
+		Config:    g.Config,
+	}
+	if g.Disposal != nil {
+		out.Disposal = append([]byte(nil), g.Disposal...)
+	}
+	// The first frame's palette serves as the global color table
+	p := palettes[0]
+	if out.Config.ColorModel != nil {
+		out.Config.ColorModel = p
+	}
+	if old, ok := g.Config.ColorModel.(color.Palette); ok && int(g.BackgroundIndex) < len(old) && len(p) > 0 {
+		out.BackgroundIndex = uint8(p.Index(old[g.BackgroundIndex]))
+	}
+	for i, m := range g.Image {
+		p := palettes[i]
+		frame := image.NewPaletted(m.Rect, p)
+		if len(p) > 0 {
+			d.Draw(frame, frame.Rect, m, m.Rect.Min)
+		}
+		if transparent := q.TransparentEntry(p); q.AddTransparent && transparent >= 0 {
+			for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+				for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+					if _, _, _, a := m.At(x, y).RGBA(); a == 0 {
+						frame.SetColorIndex(x, y, uint8(transparent))
+					}
+				}
+			}
+		}
+		out.Image[i] = frame
+	}
+	if opts.InterFrameTransparency {
+		clearUnchanged(out)
+	}
+	return out, nil
+}
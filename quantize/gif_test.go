@@ -0,0 +1,288 @@
+package quantize
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"testing"
+)
+
+func TestPlanGIF(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	flat := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		flat.Set(x, 0, color.RGBA{uint8(x * 60), 0, 0, 255})
+	}
+	frames := []image.Image{i, flat}
+
+	q := MedianCutQuantizer{}
+	plan := q.PlanGIF(frames, 16, 0)
+	if len(plan.Local) != len(frames) {
+		t.Fatal("Plan doesn't cover every frame")
+	}
+	for _, l := range plan.Local {
+		if l != nil {
+			t.Fatal("Plan exceeded a zero byte budget")
+		}
+	}
+
+	plan = q.PlanGIF(frames, 16, tableSize(16))
+	var locals int
+	for _, l := range plan.Local {
+		if l != nil {
+			locals++
+		}
+	}
+	if locals != 1 {
+		t.Fatalf("Expected one local table within budget, got %d", locals)
+	}
+}
+
+func TestPrepareFrames(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	background := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(background, background.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+	overlay := image.NewRGBA(background.Bounds())
+	draw.Draw(overlay, overlay.Bounds(), background, image.Point{}, draw.Src)
+	draw.Draw(overlay, image.Rect(2, 2, 4, 4), image.NewUniform(blue), image.Point{}, draw.Src)
+	cleared := image.NewRGBA(background.Bounds())
+	frames := []image.Image{background, overlay, background, cleared}
+
+	q := MedianCutQuantizer{AddTransparent: true}
+	plan := q.PlanGIF(frames, 4, 0)
+	g := PrepareFrames(frames, []int{10, 10, 10, 10}, plan, FrameOptions{Drawer: draw.Src})
+
+	expected := []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalBackground, gif.DisposalNone}
+	for i, d := range expected {
+		if g.Disposal[i] != d {
+			t.Fatalf("Frame %d: expected disposal %d, got %d", i, d, g.Disposal[i])
+		}
+	}
+	if g.Image[1].Bounds() != image.Rect(2, 2, 4, 4) {
+		t.Fatalf("Overlay frame was not cropped, got %v", g.Image[1].Bounds())
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal("Couldn't encode prepared frames: ", err)
+	}
+	if _, err := gif.DecodeAll(&buf); err != nil {
+		t.Fatal("Couldn't decode prepared frames: ", err)
+	}
+}
+
+func TestLossyFrames(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(a, a.Bounds(), image.NewUniform(color.RGBA{100, 100, 100, 255}), image.Point{}, draw.Src)
+	b := image.NewRGBA(a.Bounds())
+	draw.Draw(b, b.Bounds(), a, image.Point{}, draw.Src)
+	b.Set(1, 1, color.RGBA{102, 100, 100, 255})
+	b.Set(6, 6, color.RGBA{255, 255, 255, 255})
+	frames := []image.Image{a, b}
+
+	q := MedianCutQuantizer{AddTransparent: true}
+	plan := q.PlanGIF(frames, 4, 0)
+	lossless := PrepareFrames(frames, nil, plan, FrameOptions{Drawer: draw.Src})
+	lossy := PrepareFrames(frames, nil, plan, FrameOptions{Drawer: draw.Src, Lossiness: 4})
+	if lossless.Image[1].Bounds() != image.Rect(1, 1, 7, 7) {
+		t.Fatalf("Lossless frame has unexpected bounds %v", lossless.Image[1].Bounds())
+	}
+	if lossy.Image[1].Bounds() != image.Rect(6, 6, 7, 7) {
+		t.Fatalf("Lossy frame has unexpected bounds %v", lossy.Image[1].Bounds())
+	}
+}
+
+func TestMergeDuplicateFrames(t *testing.T) {
+	black := image.NewUniform(color.RGBA{0, 0, 0, 255})
+	a := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(a, a.Bounds(), black, image.Point{}, draw.Src)
+	b := image.NewRGBA(a.Bounds())
+	draw.Draw(b, b.Bounds(), black, image.Point{}, draw.Src)
+	b.Set(0, 0, color.RGBA{1, 0, 0, 255})
+	c := image.NewRGBA(a.Bounds())
+	draw.Draw(c, c.Bounds(), black, image.Point{}, draw.Src)
+	c.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	frames := []image.Image{a, a, c, c, b}
+
+	merged, delays := MergeDuplicateFrames(frames, []int{1, 2, 3, 4, 5}, 0)
+	if len(merged) != 3 || delays[0] != 3 || delays[1] != 7 || delays[2] != 5 {
+		t.Fatalf("Unexpected exact merge result %v", delays)
+	}
+
+	merged, delays = MergeDuplicateFrames([]image.Image{a, b, c}, []int{1, 2, 3}, 2)
+	if len(merged) != 2 || delays[0] != 3 || delays[1] != 3 {
+		t.Fatalf("Unexpected near-duplicate merge result %v", delays)
+	}
+}
+
+func TestQuantizeGIF(t *testing.T) {
+	warm := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{255, 128, 0, 255}, color.RGBA{255, 255, 0, 255}}
+	cool := color.Palette{color.RGBA{0, 0, 255, 255}, color.RGBA{0, 128, 255, 255}, color.RGBA{}}
+	first := image.NewPaletted(image.Rect(0, 0, 16, 16), warm)
+	second := image.NewPaletted(image.Rect(4, 4, 12, 12), cool)
+	for i := range first.Pix {
+		first.Pix[i] = uint8(i % 3)
+	}
+	for i := range second.Pix {
+		second.Pix[i] = uint8(i % 3)
+	}
+	g := &gif.GIF{
+		Image:           []*image.Paletted{first, second},
+		Delay:           []int{5, 20},
+		Disposal:        []byte{gif.DisposalNone, gif.DisposalBackground},
+		LoopCount:       3,
+		Config:          image.Config{ColorModel: warm, Width: 16, Height: 16},
+		BackgroundIndex: 2,
+	}
+	q := MedianCutQuantizer{}
+	out, err := q.QuantizeGIF(g, 8, GIFOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := out.Config.ColorModel.(color.Palette)
+	if len(p) != 6 || q.TransparentEntry(p) < 0 {
+		t.Fatalf("Expected 5 colors and a transparent entry, got %v", p)
+	}
+	if out.LoopCount != 3 || out.Delay[1] != 20 || out.Disposal[1] != gif.DisposalBackground || out.Config.Width != 16 {
+		t.Fatal("Expected animation settings to be preserved")
+	}
+	if p[out.BackgroundIndex] != warm[2] {
+		t.Fatalf("Expected the background to remain %v, got %v", warm[2], p[out.BackgroundIndex])
+	}
+	for i, frame := range out.Image {
+		if frame.Rect != g.Image[i].Rect || len(frame.Palette) != len(p) {
+			t.Fatalf("Expected frame %d to cover %v with the global palette", i, g.Image[i].Rect)
+		}
+		for j, index := range g.Image[i].Pix {
+			want := g.Image[i].Palette[index]
+			if got := frame.Palette[frame.Pix[j]]; got != want {
+				t.Fatalf("Expected pixel %d of frame %d to remain %v, got %v", j, i, want, got)
+			}
+		}
+	}
+	if err := gif.EncodeAll(&bytes.Buffer{}, out); err != nil {
+		t.Fatal(err)
+	}
+	g.Delay = g.Delay[:1]
+	if _, err := q.QuantizeGIF(g, 8, GIFOptions{}); err == nil {
+		t.Fatal("Expected an error for missing delays")
+	}
+}
+
+func TestQuantizeGIFLocalPalettes(t *testing.T) {
+	warm := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{255, 128, 0, 255}, color.RGBA{255, 255, 0, 255}}
+	cool := color.Palette{color.RGBA{0, 0, 255, 255}, color.RGBA{0, 128, 255, 255}, color.RGBA{0, 255, 255, 255}}
+	g := &gif.GIF{Delay: []int{10, 10, 10}, Config: image.Config{Width: 8, Height: 8}}
+	for _, p := range []color.Palette{warm, cool, cool} {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), p)
+		for i := range frame.Pix {
+			frame.Pix[i] = uint8(i % 3)
+		}
+		g.Image = append(g.Image, frame)
+	}
+	q := MedianCutQuantizer{}
+	for _, opts := range []GIFOptions{
+		{LocalPalettes: true, Drawer: draw.Src},
+		{LocalPalettes: true, SeedFromPrevious: true, Drawer: draw.Src},
+	} {
+		out, err := q.QuantizeGIF(g, 3, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Three colors per frame reproduce every frame exactly, which a shared palette could not
+		for i, frame := range out.Image {
+			if len(frame.Palette) != 3 {
+				t.Fatalf("Expected 3 local colors in frame %d, got %d", i, len(frame.Palette))
+			}
+			for j, index := range g.Image[i].Pix {
+				want := g.Image[i].Palette[index].(color.RGBA)
+				if got := frame.Palette[frame.Pix[j]].(color.RGBA); sqDiff(got, want) > 3 {
+					t.Fatalf("Expected pixel %d of frame %d to remain %v, got %v", j, i, want, got)
+				}
+			}
+		}
+		if err := gif.EncodeAll(&bytes.Buffer{}, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQuantizeGIFStability(t *testing.T) {
+	g := &gif.GIF{Delay: []int{10, 10}, Config: image.Config{Width: 8, Height: 8}}
+	for _, v := range []uint8{100, 110} {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.RGBA{v, v, v, 255}})
+		g.Image = append(g.Image, frame)
+	}
+	q := MedianCutQuantizer{Aggregation: Mean}
+	plain, err := q.QuantizeGIF(g, 1, GIFOptions{LocalPalettes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stable, err := q.QuantizeGIF(g, 1, GIFOptions{LocalPalettes: true, Stability: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := plain.Image[1].Palette[0].(color.RGBA); c.R != 110 {
+		t.Fatalf("Expected the second frame's own color without stability, got %v", c)
+	}
+	// The first frame's histogram carries 0.9 of the weight of the second's
+	if c := stable.Image[1].Palette[0].(color.RGBA); c.R < 104 || c.R > 106 {
+		t.Fatalf("Expected the second frame's entry to be blended with the first, got %v", c)
+	}
+}
+
+func TestQuantizeGIFInterFrameTransparency(t *testing.T) {
+	p := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	g := &gif.GIF{Delay: []int{10, 10, 10}, Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone}}
+	for _, moved := range []int{-1, 2, 5} {
+		frame := image.NewPaletted(image.Rect(0, 0, 8, 8), p)
+		if moved >= 0 {
+			frame.SetColorIndex(moved, moved, 1)
+		}
+		g.Image = append(g.Image, frame)
+	}
+	out, err := MedianCutQuantizer{}.QuantizeGIF(g, 3, GIFOptions{Drawer: draw.Src, InterFrameTransparency: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := func(i int) (n int) {
+		transparent := gifTransparent(out.Image[i].Palette)
+		for _, index := range out.Image[i].Pix {
+			if int(index) != transparent {
+				n++
+			}
+		}
+		return
+	}
+	// The second frame only differs by one pixel, but the third is drawn over a cleared canvas
+	if n := changed(0); n != 64 {
+		t.Fatalf("Expected the first frame to be drawn fully, got %d pixels", n)
+	}
+	if n := changed(1); n != 1 {
+		t.Fatalf("Expected the second frame to draw only its changed pixel, got %d", n)
+	}
+	if n := changed(2); n != 64 {
+		t.Fatalf("Expected the frame after a background disposal to be drawn fully, got %d pixels", n)
+	}
+	if err := gif.EncodeAll(&bytes.Buffer{}, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecayed(t *testing.T) {
+	if carried := decayed(nil, colorBucket{{1, color.RGBA{255, 0, 0, 255}}}, 0.5); len(carried) != 0 {
+		t.Fatalf("Expected a priority of 1 to drop out, got %v", carried)
+	}
+	carried := colorBucket{{1000, color.RGBA{255, 0, 0, 255}}}
+	frames := 0
+	for ; len(carried) > 0; frames++ {
+		if frames == 1000 {
+			t.Fatalf("Expected an absent color to drop out, still have %v", carried)
+		}
+		carried = decayed(nil, carried, 0.99)
+	}
+	t.Logf("Absent color dropped out after %d frames", frames)
+}
@@ -0,0 +1,64 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// heatStops are the colors of the heat map scale, from no error to maximum error
+var heatStops = []color.RGBA{
+	{0, 0, 0, 255},
+	{0, 0, 192, 255},
+	{192, 0, 0, 255},
+	{255, 192, 0, 255},
+	{255, 255, 255, 255},
+}
+
+// heat returns the heat map color for a value between 0 and 1
+func heat(t float64) color.RGBA {
+	if t <= 0 {
+		return heatStops[0]
+	}
+	if t >= 1 {
+		return heatStops[len(heatStops)-1]
+	}
+	pos := t * float64(len(heatStops)-1)
+	i := int(pos)
+	f := pos - float64(i)
+	a, b := heatStops[i], heatStops[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// HeatMap renders the per-pixel RGB distance between an original image and its quantized version, from black where
+// they match through blue, red and yellow to white at scale or more. If scale is zero, the largest error in the
+// image is used. Only the overlap of the two images' bounds is rendered.
+func HeatMap(orig image.Image, quantized image.Image, scale float64) *image.RGBA {
+	r := orig.Bounds().Intersect(quantized.Bounds())
+	dists := make([]float64, r.Dx()*r.Dy())
+	var largest float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			d := math.Sqrt(float64(sqDiff(rgbaAt(orig, x, y), rgbaAt(quantized, x, y))))
+			dists[(y-r.Min.Y)*r.Dx()+x-r.Min.X] = d
+			largest = math.Max(largest, d)
+		}
+	}
+	if scale <= 0 {
+		scale = largest
+	}
+	m := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var t float64
+			if scale > 0 {
+				t = dists[(y-r.Min.Y)*r.Dx()+x-r.Min.X] / scale
+			}
+			m.SetRGBA(x, y, heat(t))
+		}
+	}
+	return m
+}
@@ -0,0 +1,26 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHeatMap(t *testing.T) {
+	orig := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	quantized := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		orig.SetRGBA(x, 0, color.RGBA{uint8(x * 50), 0, 0, 255})
+		quantized.SetRGBA(x, 0, color.RGBA{0, 0, 0, 255})
+	}
+	m := HeatMap(orig, quantized, 0)
+	if c := m.RGBAAt(0, 0); c != heatStops[0] {
+		t.Fatalf("Expected matching pixels to be black, got %v", c)
+	}
+	if c := m.RGBAAt(3, 0); c != heatStops[len(heatStops)-1] {
+		t.Fatalf("Expected the largest error to be white, got %v", c)
+	}
+	if c := HeatMap(orig, quantized, 300).RGBAAt(3, 0); c == heatStops[len(heatStops)-1] {
+		t.Fatalf("Expected a larger scale to cool the largest error, got %v", c)
+	}
+}
@@ -0,0 +1,53 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// Incremental keeps a palette for a stream of screen captures, rebuilding it only once the error introduced by
+// changed regions exceeds a threshold. Each frame only the dirty rectangles reported by the caller are examined.
+type Incremental struct {
+	// The quantizer used to rebuild the palette
+	MedianCut MedianCutQuantizer
+	// The number of colors in the palette
+	NumColors int
+	// The accumulated mean squared RGB error, over the whole frame, at which the palette is rebuilt
+	Threshold float64
+
+	palette     color.Palette
+	accumulated float64
+}
+
+// Palette returns the current palette, or nil before the first call to Update
+func (s *Incremental) Palette() color.Palette {
+	return s.palette
+}
+
+// Update examines the dirty rectangles of the latest frame and returns the palette to encode it with, reporting
+// whether the palette was rebuilt. The first frame, and any frame following an empty palette, is always quantized
+// fully.
+func (s *Incremental) Update(m image.Image, dirty []image.Rectangle) (color.Palette, bool) {
+	bounds := m.Bounds()
+	if len(s.palette) > 0 && bounds.Dx()*bounds.Dy() > 0 {
+		for _, r := range dirty {
+			s.accumulated += regionError(s.palette, m, r) / float64(bounds.Dx()*bounds.Dy())
+		}
+		if s.accumulated <= s.Threshold {
+			return s.palette, false
+		}
+	}
+	s.palette = s.MedianCut.Quantize(make(color.Palette, 0, s.NumColors), m)
+	s.accumulated = 0
+	return s.palette, true
+}
+
+// regionError returns the total squared RGB error of mapping the pixels of m within r onto p
+func regionError(p color.Palette, m image.Image, r image.Rectangle) float64 {
+	var total float64
+	for _, c := range regionHistogram(m, r) {
+		entry := color.RGBAModel.Convert(p[p.Index(c.RGBA)]).(color.RGBA)
+		total += float64(sqDiff(c.RGBA, entry)) * float64(c.p)
+	}
+	return total
+}
@@ -0,0 +1,50 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(m, m.Bounds(), image.NewUniform(color.RGBA{0, 0, 128, 255}), image.Point{}, draw.Src)
+	s := &Incremental{NumColors: 16, Threshold: 100}
+	if _, rebuilt := s.Update(m, nil); !rebuilt {
+		t.Fatal("Expected the first frame to build a palette")
+	}
+
+	// A small change in a region adds little error
+	small := image.Rect(0, 0, 4, 4)
+	draw.Draw(m, small, image.NewUniform(color.RGBA{0, 0, 140, 255}), image.Point{}, draw.Src)
+	if _, rebuilt := s.Update(m, []image.Rectangle{small}); rebuilt {
+		t.Fatal("Expected a small change to keep the palette")
+	}
+
+	// A large change of color forces a rebuild
+	large := image.Rect(0, 0, 64, 32)
+	draw.Draw(m, large, image.NewUniform(color.RGBA{255, 255, 0, 255}), image.Point{}, draw.Src)
+	p, rebuilt := s.Update(m, []image.Rectangle{large})
+	if !rebuilt {
+		t.Fatal("Expected a large change to rebuild the palette")
+	}
+	if p.Index(color.RGBA{255, 255, 0, 255}) < 0 || Quality(p, m).MeanError != 0 {
+		t.Fatalf("Expected the rebuilt palette to cover the frame, got %v", p)
+	}
+	if _, rebuilt := s.Update(m, nil); rebuilt {
+		t.Fatal("Expected an unchanged frame to keep the palette")
+	}
+}
+
+func TestIncrementalEmptyPalette(t *testing.T) {
+	s := &Incremental{NumColors: 16}
+	if p, _ := s.Update(image.NewRGBA(image.Rect(0, 0, 0, 0)), nil); len(p) != 0 {
+		t.Fatalf("Expected an empty frame to produce an empty palette, got %v", p)
+	}
+	m := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	draw.Draw(m, m.Bounds(), image.NewUniform(color.RGBA{0, 0, 128, 255}), image.Point{}, draw.Src)
+	if p, rebuilt := s.Update(m, []image.Rectangle{m.Bounds()}); !rebuilt || len(p) == 0 {
+		t.Fatalf("Expected an empty palette to be rebuilt, got %v", p)
+	}
+}
@@ -0,0 +1,228 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// PaletteIndexer finds the palette entry nearest to a color using a k-d tree over the entries' 8-bit premultiplied
+// channels, taking time logarithmic in the size of the palette rather than linear like color.Palette.Index. Ties are
+// broken towards the lower index, as color.Palette.Index does.
+type PaletteIndexer struct {
+	nodes []kdNode
+	root  int
+}
+
+// kdNode is an entry of the tree, dividing its children along an axis at the entry's value
+type kdNode struct {
+	c           [4]int32
+	index       int
+	axis        int
+	left, right int
+}
+
+// NewPaletteIndexer builds an indexer for p, which must not be modified while the indexer is in use
+func NewPaletteIndexer(p color.Palette) *PaletteIndexer {
+	ix := &PaletteIndexer{nodes: make([]kdNode, len(p))}
+	order := make([]int, len(p))
+	for i, c := range p {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		ix.nodes[i] = kdNode{c: [4]int32{int32(rgba.R), int32(rgba.G), int32(rgba.B), int32(rgba.A)}, index: i}
+		order[i] = i
+	}
+	ix.root = ix.build(order)
+	return ix
+}
+
+// build arranges the nodes named by order into a subtree, returning its root or -1 if order is empty
+func (ix *PaletteIndexer) build(order []int) int {
+	if len(order) == 0 {
+		return -1
+	}
+	lo, hi := ix.nodes[order[0]].c, ix.nodes[order[0]].c
+	for _, i := range order[1:] {
+		for ch, v := range ix.nodes[i].c {
+			if v < lo[ch] {
+				lo[ch] = v
+			}
+			if v > hi[ch] {
+				hi[ch] = v
+			}
+		}
+	}
+	axis := 0
+	for ch := range lo {
+		if hi[ch]-lo[ch] > hi[axis]-lo[axis] {
+			axis = ch
+		}
+	}
+	sort.Slice(order, func(a, b int) bool { return ix.nodes[order[a]].c[axis] < ix.nodes[order[b]].c[axis] })
+	mid := len(order) / 2
+	n := &ix.nodes[order[mid]]
+	n.axis = axis
+	n.left = ix.build(order[:mid])
+	n.right = ix.build(order[mid+1:])
+	return order[mid]
+}
+
+// Index returns the index of the palette entry nearest to c, or -1 if the palette is empty
+func (ix *PaletteIndexer) Index(c color.Color) int {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return ix.indexRGBA(rgba)
+}
+
+// indexRGBA returns the index of the palette entry nearest to an 8-bit premultiplied color
+func (ix *PaletteIndexer) indexRGBA(c color.RGBA) int {
+	target := [4]int32{int32(c.R), int32(c.G), int32(c.B), int32(c.A)}
+	best, bestDist := -1, int32(0)
+	var search func(n int)
+	search = func(n int) {
+		if n < 0 {
+			return
+		}
+		node := &ix.nodes[n]
+		var dist int32
+		for ch, v := range node.c {
+			d := v - target[ch]
+			dist += d * d
+		}
+		if best < 0 || dist < bestDist || (dist == bestDist && node.index < best) {
+			best, bestDist = node.index, dist
+		}
+		near, far := node.left, node.right
+		d := target[node.axis] - node.c[node.axis]
+		if d >= 0 {
+			near, far = far, near
+		}
+		search(near)
+		// Equally distant entries across the plane may still have a lower index
+		if d*d <= bestDist {
+			search(far)
+		}
+	}
+	search(ix.root)
+	return best
+}
+
+// Remap returns m mapped onto p without dithering, finding the nearest entry for each pixel with a PaletteIndexer
+func Remap(m image.Image, p color.Palette) *image.Paletted {
+	out := image.NewPaletted(m.Bounds(), p)
+	if len(p) == 0 {
+		return out
+	}
+	remapRect(out, m, NewPaletteIndexer(p), out.Rect)
+	return out
+}
+
+// RemapDistance behaves like Remap, but maps each opaque pixel to its nearest opaque entry under metric. Each
+// distinct color is compared against every entry once, so perceptual metrics such as CIEDE2000 stay practical for
+// small palettes. Other pixels are mapped by color.Palette.Index. If metric is nil, Remap is used.
+func RemapDistance(m image.Image, p color.Palette, metric Distance) *image.Paletted {
+	if metric == nil {
+		return Remap(m, p)
+	}
+	out := image.NewPaletted(m.Bounds(), p)
+	if len(p) == 0 {
+		return out
+	}
+	entries, indices := opaqueEntries(p)
+	cache := make(map[color.RGBA]uint8)
+	for y := out.Rect.Min.Y; y < out.Rect.Max.Y; y++ {
+		for x := out.Rect.Min.X; x < out.Rect.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			index, ok := cache[c]
+			if !ok {
+				if i := nearestBy(entries, c, metric); i >= 0 && c.A == 255 {
+					index = uint8(indices[i])
+				} else {
+					index = uint8(p.Index(c))
+				}
+				cache[c] = index
+			}
+			out.Pix[out.PixOffset(x, y)] = index
+		}
+	}
+	return out
+}
+
+// remapRect maps the pixels of m within r onto out, which must share its coordinates
+func remapRect(out *image.Paletted, m image.Image, ix *PaletteIndexer, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			out.Pix[out.PixOffset(x, y)] = uint8(ix.indexRGBA(rgbaAt(m, x, y)))
+		}
+	}
+}
+
+// RemapParallel behaves like Remap, but divides the image into horizontal bands remapped by GOMAXPROCS goroutines.
+// If d is not nil each band is drawn with it instead, so error diffusion restarts at the top of every band; m must
+// then be safe for concurrent reads, as the standard library images are.
+func RemapParallel(m image.Image, p color.Palette, d draw.Drawer) *image.Paletted {
+	b := m.Bounds()
+	out := image.NewPaletted(b, p)
+	if len(p) == 0 || b.Empty() {
+		return out
+	}
+	var ix *PaletteIndexer
+	if d == nil {
+		ix = NewPaletteIndexer(p)
+	}
+	bands := runtime.GOMAXPROCS(0)
+	if bands > b.Dy() {
+		bands = b.Dy()
+	}
+	height := (b.Dy() + bands - 1) / bands
+	var wg sync.WaitGroup
+	for y := b.Min.Y; y < b.Max.Y; y += height {
+		band := image.Rect(b.Min.X, y, b.Max.X, y+height).Intersect(b)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d != nil {
+				d.Draw(out, band, m, band.Min)
+			} else {
+				remapRect(out, m, ix, band)
+			}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// InverseColormap maps colors to palette entries in constant time with a table indexed by the top 5 bits of each
+// RGB channel, as GIF encoders commonly do. Each cell holds the entry nearest to its center, so colors close to the
+// boundary between two entries may map to the slightly farther one. Alpha is ignored, so the table suits opaque
+// pixels only.
+type InverseColormap struct {
+	table [1 << 15]uint8
+}
+
+// cell returns the table index of a color
+func cell(r, g, b uint8) int {
+	return int(r>>3)<<10 | int(g>>3)<<5 | int(b>>3)
+}
+
+// NewInverseColormap builds the table for p, which must hold between 1 and 256 entries
+func NewInverseColormap(p color.Palette) *InverseColormap {
+	ix := NewPaletteIndexer(p)
+	m := &InverseColormap{}
+	for r := 0; r < 32; r++ {
+		for g := 0; g < 32; g++ {
+			for b := 0; b < 32; b++ {
+				c := color.RGBA{uint8(r<<3 | 4), uint8(g<<3 | 4), uint8(b<<3 | 4), 255}
+				m.table[cell(c.R, c.G, c.B)] = uint8(ix.indexRGBA(c))
+			}
+		}
+	}
+	return m
+}
+
+// Index returns the index of the palette entry for c
+func (m *InverseColormap) Index(c color.Color) int {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return int(m.table[cell(rgba.R, rgba.G, rgba.B)])
+}
@@ -0,0 +1,117 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// nearestRGBA returns the index of the first entry of p closest to c in 8-bit premultiplied RGBA
+func nearestRGBA(p []color.RGBA, c color.RGBA) int {
+	best, bestDist := -1, 0
+	for i, e := range p {
+		dr, dg, db, da := int(e.R)-int(c.R), int(e.G)-int(c.G), int(e.B)-int(c.B), int(e.A)-int(c.A)
+		if d := dr*dr + dg*dg + db*db + da*da; best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func TestPaletteIndexer(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 7, 64, 256} {
+		entries := make([]color.RGBA, n)
+		p := make(color.Palette, n)
+		for i := range entries {
+			// Coarse channel values produce plenty of ties
+			entries[i] = color.RGBA{uint8(r.Intn(4) * 64), uint8(r.Intn(4) * 64), uint8(r.Intn(4) * 64), 255}
+			p[i] = entries[i]
+		}
+		ix := NewPaletteIndexer(p)
+		for j := 0; j < 2000; j++ {
+			c := color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}
+			if got, want := ix.Index(c), nearestRGBA(entries, c); got != want {
+				t.Fatalf("Expected entry %d of %d for %v, got %d", want, n, c, got)
+			}
+		}
+	}
+	if i := NewPaletteIndexer(nil).Index(color.Black); i != -1 {
+		t.Fatalf("Expected -1 for an empty palette, got %d", i)
+	}
+}
+
+func TestRemap(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 64), m)
+	out := Remap(m, p)
+	entries := make([]color.RGBA, len(p))
+	for i, c := range p {
+		entries[i] = c.(color.RGBA)
+	}
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 5 {
+		for x := b.Min.X; x < b.Max.X; x += 5 {
+			if want := nearestRGBA(entries, rgbaAt(m, x, y)); int(out.ColorIndexAt(x, y)) != want {
+				t.Fatalf("Expected index %d at (%d, %d), got %d", want, x, y, out.ColorIndexAt(x, y))
+			}
+		}
+	}
+}
+
+func TestInverseColormap(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 32), m)
+	entries := make([]color.RGBA, len(p))
+	for i, c := range p {
+		entries[i] = c.(color.RGBA)
+	}
+	lut := NewInverseColormap(p)
+	r := rand.New(rand.NewSource(1))
+	for j := 0; j < 2000; j++ {
+		c := color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 255}
+		center := color.RGBA{c.R&^7 | 4, c.G&^7 | 4, c.B&^7 | 4, 255}
+		if got, want := lut.Index(c), nearestRGBA(entries, center); got != want {
+			t.Fatalf("Expected %v to map to entry %d nearest its cell center, got %d", c, want, got)
+		}
+		// The entry found is at most twice the distance to the cell center farther than the nearest one
+		got, want := entries[lut.Index(c)], entries[nearestRGBA(entries, c)]
+		if math.Sqrt(float64(sqDiff(c, got))) > math.Sqrt(float64(sqDiff(c, want)))+2*math.Sqrt(3*4*4) {
+			t.Fatalf("Expected %v to map close to %v, got %v", c, want, got)
+		}
+	}
+
+	q := MedianCutQuantizer{InverseColormap: true}
+	exact := MedianCutQuantizer{}.Paletted(m, 32, nil)
+	approx := q.Paletted(m, 32, nil)
+	meanError := func(out *image.Paletted) float64 {
+		var sum float64
+		b := out.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				sum += math.Sqrt(float64(sqDiff(rgbaAt(m, x, y), entries[out.ColorIndexAt(x, y)])))
+			}
+		}
+		return sum / float64(b.Dx()*b.Dy())
+	}
+	if e, a := meanError(exact), meanError(approx); a > e*1.1 {
+		t.Fatalf("Expected the lookup table to add little error, got %f over %f", a, e)
+	}
+}
+
+func TestRemapParallel(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg")
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 64), m)
+	if string(RemapParallel(m, p, nil).Pix) != string(Remap(m, p).Pix) {
+		t.Fatal("Expected parallel remapping to match Remap")
+	}
+	dithered := RemapParallel(m, p, FloydSteinberg{})
+	serial := image.NewPaletted(m.Bounds(), p)
+	FloydSteinberg{}.Draw(serial, serial.Rect, m, serial.Rect.Min)
+	// The first band is dithered exactly as a single pass would
+	if row := serial.Stride; string(dithered.Pix[:row]) != string(serial.Pix[:row]) {
+		t.Fatal("Expected the first row to match serial dithering")
+	}
+}
@@ -0,0 +1,229 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// defaultIterations is the maximum number of iterations performed by refiners when none is configured
+const defaultIterations = 10
+
+// Convergence controls how long iterative refiners run
+type Convergence struct {
+	// The maximum number of iterations to run; if zero a default of 10 is used
+	MaxIterations int
+	// Refinement stops once no palette entry moves further than this RGB distance in a single iteration
+	Epsilon float64
+	// If set, called after each iteration with the iteration number, starting at zero, and the weighted mean
+	// squared RGB error of the palette measured during that iteration. Returning false stops refinement.
+	Progress func(iteration int, meanError float64) bool
+}
+
+func (c Convergence) iterations() int {
+	if c.MaxIterations > 0 {
+		return c.MaxIterations
+	}
+	return defaultIterations
+}
+
+// KMeansInit specifies how the initial k-means centers are chosen
+type KMeansInit uint8
+
+const (
+	// MedianCutInit - seed the centers with a median cut palette
+	MedianCutInit KMeansInit = iota
+	// KMeansPlusPlus - seed the centers with k-means++ sampling over the histogram
+	KMeansPlusPlus
+)
+
+// KMeansQuantizer implements the go draw.Quantizer interface using weighted k-means clustering of the image histogram
+type KMeansQuantizer struct {
+	// Histogram, transparency and median cut seeding options
+	MedianCut MedianCutQuantizer
+	// The method used to choose the initial centers
+	Init KMeansInit
+	// The random seed used by k-means++ initialization, making results reproducible
+	Seed int64
+	// When to stop refining
+	Convergence
+}
+
+type center [3]float64
+
+func centerOf(c color.RGBA) center {
+	return center{float64(c.R), float64(c.G), float64(c.B)}
+}
+
+func (c center) rgba() color.RGBA {
+	return color.RGBA{uint8(c[0] + 0.5), uint8(c[1] + 0.5), uint8(c[2] + 0.5), 255}
+}
+
+func (c center) dist(o color.RGBA) float64 {
+	dr := c[0] - float64(o.R)
+	dg := c[1] - float64(o.G)
+	db := c[2] - float64(o.B)
+	return dr*dr + dg*dg + db*db
+}
+
+func (c center) distTo(o center) float64 {
+	d0, d1, d2 := c[0]-o[0], c[1]-o[1], c[2]-o[2]
+	return d0*d0 + d1*d1 + d2*d2
+}
+
+// nearestCenter returns the index of the center closest to c and its squared distance
+func nearestCenter(centers []center, c color.RGBA) (int, float64) {
+	best, bestDist := 0, centers[0].dist(c)
+	for i := 1; i < len(centers); i++ {
+		if d := centers[i].dist(c); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best, bestDist
+}
+
+// kmeansPlusPlus chooses up to k initial centers from the histogram, each sampled with probability proportional to
+// its priority times its squared distance from the centers chosen so far
+func kmeansPlusPlus(colors colorBucket, k int, r *rand.Rand) []center {
+	if len(colors) == 0 || k <= 0 {
+		return nil
+	}
+	var total float64
+	for _, c := range colors {
+		total += float64(c.p)
+	}
+	pick := func(weights []float64, total float64) int {
+		target := r.Float64() * total
+		for i, w := range weights {
+			if target < w {
+				return i
+			}
+			target -= w
+		}
+		return len(weights) - 1
+	}
+	weights := make([]float64, len(colors))
+	for i, c := range colors {
+		weights[i] = float64(c.p)
+	}
+	centers := make([]center, 0, k)
+	centers = append(centers, centerOf(colors[pick(weights, total)].RGBA))
+	dists := make([]float64, len(colors))
+	for i, c := range colors {
+		dists[i] = centers[0].dist(c.RGBA)
+	}
+	for len(centers) < k {
+		total = 0
+		for i, c := range colors {
+			weights[i] = float64(c.p) * dists[i]
+			total += weights[i]
+		}
+		if total == 0 {
+			break // Every color is already a center
+		}
+		next := centerOf(colors[pick(weights, total)].RGBA)
+		centers = append(centers, next)
+		for i, c := range colors {
+			if d := next.dist(c.RGBA); d < dists[i] {
+				dists[i] = d
+			}
+		}
+	}
+	return centers
+}
+
+// kmeans refines centers with weighted Lloyd iterations over the histogram
+func kmeans(colors colorBucket, centers []center, conv Convergence) []center {
+	if len(colors) == 0 || len(centers) == 0 {
+		return centers
+	}
+	sums := make([]center, len(centers))
+	weights := make([]float64, len(centers))
+	for iter := 0; iter < conv.iterations(); iter++ {
+		for i := range sums {
+			sums[i] = center{}
+			weights[i] = 0
+		}
+		var errSum, total float64
+		for _, c := range colors {
+			i, d := nearestCenter(centers, c.RGBA)
+			w := float64(c.p)
+			sums[i][0] += float64(c.R) * w
+			sums[i][1] += float64(c.G) * w
+			sums[i][2] += float64(c.B) * w
+			weights[i] += w
+			errSum += d * w
+			total += w
+		}
+		var moved float64
+		for i := range centers {
+			if weights[i] == 0 {
+				continue // Keep empty clusters where they are
+			}
+			next := center{sums[i][0] / weights[i], sums[i][1] / weights[i], sums[i][2] / weights[i]}
+			moved = math.Max(moved, math.Sqrt(next.distTo(centers[i])))
+			centers[i] = next
+		}
+		if conv.Progress != nil && !conv.Progress(iter, errSum/total) {
+			break
+		}
+		if moved <= conv.Epsilon {
+			break
+		}
+	}
+	return centers
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q KMeansQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	numColors, addTransparent := q.MedianCut.target(p)
+	var centers []center
+	switch q.Init {
+	case KMeansPlusPlus:
+		centers = kmeansPlusPlus(bucket, numColors, rand.New(rand.NewSource(q.Seed)))
+	default:
+		for _, c := range q.MedianCut.palettize(nil, q.MedianCut.bucketize(bucket, numColors)) {
+			centers = append(centers, centerOf(c.(color.RGBA)))
+		}
+	}
+	for _, c := range kmeans(bucket, centers, q.Convergence) {
+		p = append(p, c.rgba())
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
+
+// Refine returns a copy of p with its opaque entries moved towards the colors of m by weighted k-means over the
+// image's histogram, running KMeansIterations iterations or 10 if unset. The palette never grows and entries that
+// aren't fully opaque are kept as they are, so a palette can be refined progressively over the frames of a video or
+// starting from colors supplied by the user.
+func (q MedianCutQuantizer) Refine(p color.Palette, m image.Image) color.Palette {
+	out := append(color.Palette(nil), p...)
+	var index []int
+	var centers []center
+	for i, c := range p {
+		if rgba := color.RGBAModel.Convert(c).(color.RGBA); rgba.A == 255 {
+			index = append(index, i)
+			centers = append(centers, centerOf(q.ColorSpace.encode(rgba)))
+		}
+	}
+	if len(centers) == 0 {
+		return out
+	}
+	bucket := q.buildBucket(m)
+	defer q.putBucket(bucket)
+	if q.ColorSpace != RGB {
+		for i := range bucket {
+			bucket[i].RGBA = q.ColorSpace.encode(bucket[i].RGBA)
+		}
+	}
+	for i, c := range kmeans(bucket, centers, Convergence{MaxIterations: q.KMeansIterations}) {
+		out[index[i]] = q.ColorSpace.decode(c.rgba())
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestKMeansQuantize(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+
+	mc := MedianCutQuantizer{Aggregation: Mean}
+	base := Quality(mc.Quantize(make([]color.Color, 0, 32), i), i)
+
+	q := KMeansQuantizer{MedianCut: mc}
+	p := q.Quantize(make([]color.Color, 0, 32), i)
+	if len(p) != 32 {
+		t.Fatalf("Expected 32 colors, got %d", len(p))
+	}
+	refined := Quality(p, i)
+	t.Logf("Median cut error %f, k-means error %f", base.MeanError, refined.MeanError)
+	if refined.MeanError > base.MeanError {
+		t.Fatal("K-means refinement increased error")
+	}
+}
+
+func TestKMeansPlusPlus(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := KMeansQuantizer{Init: KMeansPlusPlus, Seed: 42}
+	p := q.Quantize(make([]color.Color, 0, 32), i)
+	p2 := q.Quantize(make([]color.Color, 0, 32), i)
+	if len(p) != 32 || len(p2) != 32 {
+		t.Fatal("K-means++ produced the wrong number of colors")
+	}
+	for i := range p {
+		if p[i] != p2[i] {
+			t.Fatal("K-means++ is not reproducible for a fixed seed")
+		}
+	}
+	t.Logf("K-means++ error %f", Quality(p, i).MeanError)
+}
+
+func TestKMeansConvergence(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := KMeansQuantizer{Convergence: Convergence{MaxIterations: 1}}
+	one := Quality(q.Quantize(make([]color.Color, 0, 32), i), i)
+
+	q = KMeansQuantizer{Convergence: Convergence{MaxIterations: 50, Epsilon: 0.5}}
+	many := Quality(q.Quantize(make([]color.Color, 0, 32), i), i)
+	t.Logf("One iteration error %f, converged error %f", one.MeanError, many.MeanError)
+	if many.MeanError > one.MeanError {
+		t.Fatal("More iterations increased error")
+	}
+}
+
+func TestKMeansProgress(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	var errors []float64
+	q := KMeansQuantizer{Convergence: Convergence{MaxIterations: 20, Progress: func(iteration int, meanError float64) bool {
+		if iteration != len(errors) {
+			t.Fatalf("Iteration %d reported out of order", iteration)
+		}
+		errors = append(errors, meanError)
+		return iteration < 2
+	}}}
+	q.Quantize(make([]color.Color, 0, 32), i)
+	if len(errors) != 3 {
+		t.Fatalf("Expected refinement to stop after 3 iterations, got %d", len(errors))
+	}
+	for i := 1; i < len(errors); i++ {
+		if errors[i] > errors[i-1] {
+			t.Fatalf("Error increased between iterations: %v", errors)
+		}
+	}
+}
+
+func TestRefine(t *testing.T) {
+	colors := []color.RGBA{{200, 30, 30, 255}, {30, 200, 30, 255}, {30, 30, 200, 255}, {220, 220, 220, 255}}
+	m := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range m.Pix {
+		c := colors[i/4%len(colors)]
+		m.Pix[i] = []uint8{c.R, c.G, c.B, c.A}[i%4]
+	}
+	start := color.Palette{
+		color.RGBA{128, 0, 0, 255},
+		color.RGBA{0, 128, 0, 255},
+		color.RGBA{0, 0, 128, 255},
+		color.RGBA{128, 128, 128, 255},
+		color.RGBA{0, 0, 0, 0},
+	}
+	for _, q := range []MedianCutQuantizer{{}, {ColorSpace: YCbCr, KMeansIterations: 3}} {
+		p := q.Refine(start, m)
+		if len(p) != len(start) {
+			t.Fatalf("Expected refinement to keep %d entries, got %d", len(start), len(p))
+		}
+		if p[4] != start[4] {
+			t.Fatalf("Expected the transparent entry to be kept, got %v", p[4])
+		}
+		for i, c := range colors {
+			if d := sqDiff(c, p[i].(color.RGBA)); d > 12 {
+				t.Fatalf("Expected entry %d to move to %v, got %v", i, c, p[i])
+			}
+		}
+		if start[0] != (color.RGBA{128, 0, 0, 255}) {
+			t.Fatal("Refine modified the palette it was given")
+		}
+	}
+}
@@ -5,39 +5,10 @@ package quantize
 import (
 	"image"
 	"image/color"
-	"sync"
+	"image/draw"
+	"math"
 )
 
-type bucketPool struct {
-	sync.Pool
-	maxCap int
-	m      sync.Mutex
-}
-
-func (p *bucketPool) getBucket(c int) colorBucket {
-	p.m.Lock()
-	if p.maxCap > c {
-		p.maxCap = p.maxCap * 99 / 100
-	}
-	if p.maxCap < c {
-		p.maxCap = c
-	}
-	maxCap := p.maxCap
-	p.m.Unlock()
-	val := p.Pool.Get()
-	if val == nil || cap(val.(colorBucket)) < c {
-		return make(colorBucket, maxCap)[0:c]
-	}
-	slice := val.(colorBucket)
-	slice = slice[0:c]
-	for i := range slice {
-		slice[i] = colorPriority{}
-	}
-	return slice
-}
-
-var bpool bucketPool
-
 // AggregationType specifies the type of aggregation to be done
 type AggregationType uint8
 
@@ -48,6 +19,116 @@ const (
 	Mean
 )
 
+// PartitionType specifies where buckets are divided along their split axis
+type PartitionType uint8
+
+const (
+	// ApproximateMedian - split around the weighted point found while measuring the bucket. This is fast but
+	// can produce unbalanced buckets when many colors share values near the median
+	ApproximateMedian PartitionType = iota
+	// WeightedMedian - sort each bucket along its split axis and split at the exact weighted median. This
+	// produces balanced buckets at the cost of an extra counting sort pass per split
+	WeightedMedian
+)
+
+// SplitStrategy specifies which bucket is divided next
+type SplitStrategy uint8
+
+const (
+	// LongestAxis - split buckets in the order they were created, each along its longest axis
+	LongestAxis SplitStrategy = iota
+	// LargestVariance - split the bucket with the largest priority-weighted color variance, which reduces banding
+	// on gradients
+	LargestVariance
+	// LargestProduct - split the bucket with the largest product of total priority and longest axis span
+	LargestProduct
+)
+
+// Rounding specifies how channels deeper than 8 bits are reduced when building the histogram
+type Rounding uint8
+
+const (
+	// Truncate - keep the high byte of each channel, matching color.RGBAModel
+	Truncate Rounding = iota
+	// Nearest - round each channel to the nearest 8-bit value
+	Nearest
+)
+
+// Alignment specifies how the requested number of colors is rounded to a power of two
+type Alignment uint8
+
+const (
+	// NoAlignment - use the requested number of colors as is
+	NoAlignment Alignment = iota
+	// AlignDown - round the number of colors down to a power of two
+	AlignDown
+	// AlignUp - round the number of colors up to a power of two, growing the palette past its capacity if needed
+	AlignUp
+)
+
+// LumaWeights specifies whether and how colors are collapsed to gray
+type LumaWeights uint8
+
+const (
+	// NoGrayscale - quantize in color
+	NoGrayscale LumaWeights = iota
+	// Rec601 - collapse colors to gray with the Rec. 601 luma weights used by JPEG and standard definition video
+	Rec601
+	// Rec709 - collapse colors to gray with the Rec. 709 luma weights used by sRGB and high definition video
+	Rec709
+)
+
+// luma returns the gray level of an RGB color under the weights
+func (w LumaWeights) luma(c color.RGBA) uint8 {
+	if w == Rec709 {
+		return uint8((13933*uint32(c.R) + 46871*uint32(c.G) + 4732*uint32(c.B) + 1<<15) >> 16)
+	}
+	return uint8((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+}
+
+// Precision specifies the channel depth of the display a palette is meant for
+type Precision uint8
+
+const (
+	// FullPrecision - keep 8 bits per channel
+	FullPrecision Precision = iota
+	// RGB565 - 5 bits of red and blue and 6 bits of green, as used by most 16-bit LCD controllers
+	RGB565
+	// RGB555 - 5 bits per channel, as used by 15-bit displays and RGBA5551 textures
+	RGB555
+)
+
+// snap rounds a color to the precision, expanding the channels back to 8 bits by bit replication as displays do
+func (p Precision) snap(c color.RGBA) color.RGBA {
+	if p == FullPrecision {
+		return c
+	}
+	five := func(v uint8) uint8 {
+		v = uint8((uint32(v)*31 + 127) / 255)
+		return v<<3 | v>>2
+	}
+	six := func(v uint8) uint8 {
+		v = uint8((uint32(v)*63 + 127) / 255)
+		return v<<2 | v>>4
+	}
+	c.R, c.B = five(c.R), five(c.B)
+	if p == RGB565 {
+		c.G = six(c.G)
+	} else {
+		c.G = five(c.G)
+	}
+	return c
+}
+
+// BitDepth returns the number of bits needed to index a palette of n colors, which is at least 1
+func BitDepth(n int) int {
+	bits := 1
+	for 1<<uint(bits) < n {
+		bits++
+	}
+	return bits
+}
+
 // MedianCutQuantizer implements the go draw.Quantizer interface using the Median Cut method
 type MedianCutQuantizer struct {
 	// The type of aggregation to be used to find final colors
@@ -56,39 +137,257 @@ type MedianCutQuantizer struct {
 	Weighting func(image.Image, int, int) uint32
 	// Whether to create a transparent entry
 	AddTransparent bool
+	// Whether to fill spare palette slots with colors interpolated between existing entries
+	// when the image has fewer colors than requested
+	FillUnused bool
+	// The number of colors to add to the palette, including any transparent entry.
+	// If zero, the remaining capacity of the palette is used.
+	MaxColors int
+	// When nonzero, colors within this RGB distance of entries already present in the palette are
+	// down-weighted in proportion to their proximity, so new entries complement the existing ones
+	ComplementRadius float64
+	// When nonzero, colors within this RGB distance of entries already present in the palette are assigned to those
+	// entries and left out of the histogram, so new entries are only spent on regions the palette doesn't cover.
+	// Applied before ComplementRadius
+	CoverRadius float64
+	// Colors guaranteed to appear verbatim in the palette, such as brand colors or pure black and white. They are added
+	// before quantizing and count towards the number of colors, and the pixels they match exactly, or within
+	// CoverRadius, are left out of the histogram
+	ReservedColors []color.Color
+	// Where buckets are divided along their split axis
+	Partition PartitionType
+	// The color stored in the transparent entry. If nil, fully transparent black is used
+	TransparentColor color.Color
+	// An optional cache consulted before building the histogram
+	Cache Cache
+	// Optional buffers reused across calls to Quantize instead of the shared pool
+	Scratch *Scratch
+	// Optional caller-owned memory histograms are carved from instead of the shared pool
+	Arena *Arena
+	// How 16-bit channels are reduced to the 8 bits the histogram holds
+	Rounding Rounding
+	// How the number of colors, including any transparent entry, is rounded to a power of two. Images with fewer
+	// colors still produce short palettes unless FillUnused is set
+	Align Alignment
+	// When nonzero, pixels with fewer than this many similar colors among their 8 neighbors are left out of the
+	// histogram, so isolated hot pixels and compression artifacts can't claim palette entries
+	OutlierSupport int
+	// When nonzero, the number of bits of chroma kept in the histogram. Luma keeps full precision, so photographic
+	// inputs produce far fewer distinct colors while gradients remain smooth
+	ChromaBits int
+	// Whether colors are collapsed to luma before bucketing, and with which weights, producing a gray palette for
+	// targets such as e-ink panels and thermal printers. Paletted maps and dithers the luma of each pixel
+	Grayscale LumaWeights
+	// The channel depth entries are rounded to, so the palette can be loaded into 16-bit display hardware as is.
+	// Entries that round to the same color are merged, so the palette may be shorter than requested
+	Precision Precision
+	// Whether the histogram is also rounded to Precision before bucketing, so buckets are formed from colors the
+	// display can show and fewer entries are merged
+	SnapHistogram bool
+	// The metric Paletted uses to map opaque pixels to opaque entries when no drawer is given. If nil, Euclidean RGB
+	// distance is used, or OKLab distance when ColorSpace is OKLab
+	Distance Distance
+	// The coordinates buckets are split and aggregated in. Only the final palette is converted back to RGB
+	ColorSpace ColorSpace
+	// When nonzero, pixels with alpha below this value are left out of the histogram, as they are expected to map
+	// to the transparent entry, while pixels at or above it are treated as opaque unless QuantizeAlpha is set. This
+	// places the cutoff for formats with binary transparency such as GIF
+	AlphaThreshold uint8
+	// Whether Quantize should inspect the image's alpha channel to decide AddTransparent and AlphaThreshold,
+	// overriding their values
+	AutoTransparent bool
+	// When greater than 1, the priority of highly saturated pixels surrounded by similar colors is multiplied by
+	// this value, so small coherent regions such as logos and icons keep their exact colors instead of being
+	// averaged into the background
+	SaturationBoost uint32
+	// When nonzero, Mean entries are resaturated to keep at least this fraction, between 0 and 1, of the chroma
+	// of the most saturated color they represent, countering the washed-out look of averaging colorful buckets
+	MinChroma float64
+	// Shifts palette entries towards shadows when negative or highlights when positive, by weighting pixels along a
+	// luma curve. At -1 or 1 the darkest or lightest pixels count four times as much as the opposite extreme
+	ToneBias float64
+	// When nonzero, the number of weighted k-means iterations run over the histogram to refine the median cut
+	// palette. A few iterations noticeably lower the error on photographic input
+	KMeansIterations int
+	// Which bucket is divided next
+	Split SplitStrategy
+	// When nonzero, buckets whose total priority is below this value are not split further, so small clusters of
+	// noise can't take entries from dominant colors. The palette may be shorter than requested as a result
+	MinBucketWeight uint64
+	// Whether histograms are put in a canonical order and ties between equally weighted colors are broken by color
+	// value, so the same pixels always produce identical palettes regardless of image size or the order images are
+	// combined in. This costs a sort of every histogram
+	Deterministic bool
+	// Whether Paletted maps opaque pixels through an InverseColormap when no drawer is given, trading exactness near
+	// the boundaries between entries for constant-time lookups
+	InverseColormap bool
+	// When nonzero, QuantizeStream keeps each frame's histogram and its combined histogram to at most this many
+	// colors, merging groups of nearby colors into their weighted mean whenever one grows past the limit, so memory
+	// stays flat however many frames are read
+	MaxBins int
+	// Whether alpha is split and aggregated like the color channels, producing semi-transparent entries for formats
+	// such as PNG-8 that support them. Otherwise Mean entries are opaque. KMeansIterations is ignored when set
+	QuantizeAlpha bool
+	// Whether semi-transparent pixels contribute their straight rather than premultiplied color to the histogram, so
+	// antialiased edges don't pull the palette toward black. Entries are still premultiplied
+	StraightAlpha bool
+	// Whether the added transparent entry is inserted at TransparentIndex of the returned palette, shifting the entries
+	// after it, rather than appended. GIF encoders often expect it at index 0
+	PlaceTransparent bool
+	// The index the transparent entry is inserted at when PlaceTransparent is set. Indices past the end append it
+	TransparentIndex int
+
+	// When nonzero, bucketize stops splitting once the mean distance between colors and their entries reaches it
+	maxError float64
 }
 
-//bucketize takes a bucket and performs median cut on it to obtain the target number of grouped buckets
-func bucketize(colors colorBucket, num int) (buckets []colorBucket) {
-	if len(colors) == 0 || num == 0 {
+// bucketize takes a bucket and performs median cut on it to obtain the target number of grouped buckets.
+// Exactly min(num, len(colors)) buckets are returned, since every split produces two non-empty buckets, unless
+// MinBucketWeight leaves no bucket that can be split.
+func (q MedianCutQuantizer) bucketize(colors colorBucket, num int) (buckets []colorBucket) {
+	if len(colors) == 0 || num <= 0 {
 		return nil
 	}
+	if q.Split != LongestAxis || q.maxError > 0 {
+		return q.bucketizeRanked(colors, num)
+	}
 	bucket := colors
-	buckets = make([]colorBucket, 1, num*2)
-	buckets[0] = bucket
+	buckets = append(q.bucketQueue(num*2), bucket)
 
-	for len(buckets) < num && len(buckets) < len(colors) { // Limit to palette capacity or number of colors
+	stuck := 0 // Buckets passed over since the last split
+	// Limit to palette capacity or number of colors, and stop once no bucket can be split
+	for len(buckets) < num && len(buckets) < len(colors) && stuck < len(buckets) {
 		bucket, buckets = buckets[0], buckets[1:]
-		if len(bucket) < 2 {
+		if !q.splittable(bucket) {
 			buckets = append(buckets, bucket)
-			continue
-		} else if len(bucket) == 2 {
-			buckets = append(buckets, bucket[:1], bucket[1:])
+			stuck++
 			continue
 		}
-
-		left, right := bucket.partition()
+		left, right := q.split(bucket)
 		buckets = append(buckets, left, right)
+		stuck = 0
 	}
 	return
 }
 
+// splittable returns whether a bucket holds at least two colors and at least MinBucketWeight priority
+func (q MedianCutQuantizer) splittable(bucket colorBucket) bool {
+	if len(bucket) < 2 {
+		return false
+	}
+	if q.MinBucketWeight == 0 {
+		return true
+	}
+	var total uint64
+	for _, c := range bucket {
+		total += uint64(c.p)
+	}
+	return total >= q.MinBucketWeight
+}
+
+// weights returns the relative importance of each axis when choosing where to split
+func (q MedianCutQuantizer) weights() [4]uint32 {
+	w := q.ColorSpace.weights()
+	if q.QuantizeAlpha {
+		w[alpha] = w[red]
+	}
+	return w
+}
+
+// split divides a bucket of at least two colors using the configured partition
+func (q MedianCutQuantizer) split(bucket colorBucket) (colorBucket, colorBucket) {
+	if len(bucket) == 2 {
+		return bucket[:1], bucket[1:]
+	}
+	var left, right colorBucket
+	switch q.Partition {
+	case WeightedMedian:
+		left, right = bucket.medianPartition(q.weights())
+	default:
+		left, right = bucket.partition(q.weights())
+	}
+	if len(left) == 0 || len(right) == 0 {
+		left, right = bucket.populationPartition()
+	}
+	return left, right
+}
+
+// score ranks a bucket for splitting under the configured strategy. Buckets of a single color score zero
+func (q MedianCutQuantizer) score(bucket colorBucket) float64 {
+	if len(bucket) < 2 {
+		return 0
+	}
+	if q.maxError > 0 {
+		return q.bucketError(bucket)
+	}
+	switch q.Split {
+	case LargestVariance:
+		return bucket.variance()
+	default:
+		var total float64
+		for _, c := range bucket {
+			total += float64(c.p)
+		}
+		_, _, width := bucket.span(q.weights())
+		return total * float64(width)
+	}
+}
+
+// bucketError returns the priority-weighted sum of distances between the colors of a bucket and its palette entry
+func (q MedianCutQuantizer) bucketError(bucket colorBucket) float64 {
+	entry := centerOf(q.palettize(nil, []colorBucket{bucket})[0].(color.RGBA))
+	var sum float64
+	for _, c := range bucket {
+		sum += float64(c.p) * math.Sqrt(entry.dist(c.RGBA))
+	}
+	return sum
+}
+
+// bucketizeRanked behaves like bucketize, but always splits the highest scoring bucket next. When maxError is set,
+// buckets are scored by their error and splitting stops once the mean error reaches it.
+func (q MedianCutQuantizer) bucketizeRanked(colors colorBucket, num int) []colorBucket {
+	buckets := append(q.bucketQueue(num), colors)
+	scores := []float64{q.score(colors)}
+	var limit, sum float64
+	if q.maxError > 0 {
+		for _, c := range colors {
+			limit += float64(c.p)
+		}
+		limit *= q.maxError
+		sum = scores[0]
+	}
+	for len(buckets) < num && (q.maxError == 0 || sum > limit) {
+		best := -1
+		for i, b := range buckets {
+			if q.splittable(b) && (best < 0 || scores[i] > scores[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		left, right := q.split(buckets[best])
+		sum -= scores[best]
+		buckets[best], scores[best] = left, q.score(left)
+		buckets = append(buckets, right)
+		scores = append(scores, q.score(right))
+		sum += scores[best] + scores[len(scores)-1]
+	}
+	return buckets
+}
+
 // palettize finds a single color to represent a set of color buckets
 func (q MedianCutQuantizer) palettize(p color.Palette, buckets []colorBucket) color.Palette {
 	for _, bucket := range buckets {
 		switch q.Aggregation {
 		case Mean:
 			mean := bucket.mean()
+			if q.MinChroma > 0 {
+				mean = q.resaturate(mean, bucket)
+			}
+			if q.QuantizeAlpha {
+				mean.A = bucket.meanAlpha()
+			}
 			p = append(p, mean)
 		case Mode:
 			var best colorPriority
@@ -103,28 +402,282 @@ func (q MedianCutQuantizer) palettize(p color.Palette, buckets []colorBucket) co
 	return p
 }
 
-// quantizeSlice expands the provided bucket and then palettizes the result
-func (q MedianCutQuantizer) quantizeSlice(p color.Palette, colors []colorPriority) color.Palette {
-	numColors := cap(p) - len(p)
-	addTransparent := q.AddTransparent
-	if addTransparent {
-		for _, c := range p {
-			if _, _, _, a := c.RGBA(); a == 0 {
-				addTransparent = false
+// chroma returns the distance of a color from the gray axis, along with its mean channel value
+func chroma(c color.RGBA) (float64, float64) {
+	l := (float64(c.R) + float64(c.G) + float64(c.B)) / 3
+	dr, dg, db := float64(c.R)-l, float64(c.G)-l, float64(c.B)-l
+	return math.Sqrt(dr*dr + dg*dg + db*db), l
+}
+
+// resaturate scales the chroma of a bucket's mean up to MinChroma of the bucket's most saturated color
+func (q MedianCutQuantizer) resaturate(mean color.RGBA, bucket colorBucket) color.RGBA {
+	var most float64
+	for _, c := range bucket {
+		if ch, _ := chroma(q.ColorSpace.decode(c.RGBA)); ch > most {
+			most = ch
+		}
+	}
+	rgb := q.ColorSpace.decode(mean)
+	ch, l := chroma(rgb)
+	target := most * q.MinChroma
+	if ch == 0 || ch >= target {
+		return mean
+	}
+	k := target / ch
+	scale := func(v uint8) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(255, l+(float64(v)-l)*k))))
+	}
+	return q.ColorSpace.encode(color.RGBA{scale(rgb.R), scale(rgb.G), scale(rgb.B), mean.A})
+}
+
+// fillUnused appends up to n colors to p, each the midpoint of the two opaque entries separated by the widest gap
+func fillUnused(p color.Palette, n int) color.Palette {
+	entries := make([]color.RGBA, 0, len(p)+n)
+	for _, c := range p {
+		if rgba := color.RGBAModel.Convert(c).(color.RGBA); rgba.A == 255 {
+			entries = append(entries, rgba)
+		}
+	}
+	for ; n > 0 && len(entries) >= 2; n-- {
+		// Find the entry whose nearest neighbor is furthest away
+		var a, b color.RGBA
+		var gap uint32
+		for i, c := range entries {
+			nearest, best := -1, uint32(0)
+			for j, o := range entries {
+				if d := sqDiff(c, o); i != j && (nearest < 0 || d < best) {
+					nearest, best = j, d
+				}
+			}
+			if best > gap {
+				a, b, gap = c, entries[nearest], best
+			}
+		}
+		mid := color.RGBA{
+			uint8((uint16(a.R) + uint16(b.R)) / 2),
+			uint8((uint16(a.G) + uint16(b.G)) / 2),
+			uint8((uint16(a.B) + uint16(b.B)) / 2),
+			255,
+		}
+		if mid == a || mid == b {
+			break // Entries are too close to interpolate between
+		}
+		entries = append(entries, mid)
+		p = append(p, mid)
+	}
+	return p
+}
+
+// snapPalette rounds the entries of p from start on to the precision, dropping those that duplicate an earlier entry
+func snapPalette(p color.Palette, start int, precision Precision) color.Palette {
+	seen := make(map[color.RGBA]bool, len(p))
+	for _, c := range p[:start] {
+		seen[color.RGBAModel.Convert(c).(color.RGBA)] = true
+	}
+	out := p[:start]
+	for _, c := range p[start:] {
+		snapped := precision.snap(color.RGBAModel.Convert(c).(color.RGBA))
+		if !seen[snapped] {
+			seen[snapped] = true
+			out = append(out, snapped)
+		}
+	}
+	return out
+}
+
+// reserve appends the reserved colors missing from p, returning the palette and the number of entries added
+func (q MedianCutQuantizer) reserve(p color.Palette) (color.Palette, int) {
+	n := len(p)
+	for _, c := range q.ReservedColors {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		present := false
+		for _, e := range p {
+			present = present || color.RGBAModel.Convert(e) == rgba
+		}
+		if !present {
+			p = append(p, rgba)
+		}
+	}
+	return p, len(p) - n
+}
+
+// cover removes the colors within radius of an entry of p, which already represents them
+func cover(colors colorBucket, p color.Palette, radius float64) colorBucket {
+	existing := make([]color.RGBA, len(p))
+	for i, c := range p {
+		existing[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	limit := radius * radius
+	kept := colors[:0]
+	for _, c := range colors {
+		covered := false
+		for _, e := range existing {
+			if float64(sqDiff(c.RGBA, e)) <= limit {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// complement scales the priority of each color by its distance to the nearest entry of p, relative to radius,
+// removing colors that are covered exactly
+func complement(colors colorBucket, p color.Palette, radius float64) colorBucket {
+	existing := make([]color.RGBA, len(p))
+	for i, c := range p {
+		existing[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	kept := colors[:0]
+	for _, c := range colors {
+		nearest := ^uint32(0)
+		for _, e := range existing {
+			if d := sqDiff(c.RGBA, e); d < nearest {
+				nearest = d
 			}
 		}
+		if d := math.Sqrt(float64(nearest)); d < radius {
+			c.p = uint32(float64(c.p) * d / radius)
+		}
+		if c.p != 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// transparentColor returns the color stored in the transparent entry
+func (q MedianCutQuantizer) transparentColor() color.Color {
+	if q.TransparentColor == nil {
+		return color.RGBA{0, 0, 0, 0}
+	}
+	return q.TransparentColor
+}
+
+// withTransparent adds the transparent entry to p, at TransparentIndex if PlaceTransparent is set
+func (q MedianCutQuantizer) withTransparent(p color.Palette) color.Palette {
+	i := len(p)
+	if q.PlaceTransparent && q.TransparentIndex >= 0 && q.TransparentIndex < i {
+		i = q.TransparentIndex
+	}
+	p = append(p, nil)
+	copy(p[i+1:], p[i:])
+	p[i] = q.transparentColor()
+	return p
+}
+
+// TransparentEntry returns the index of the last entry in p that is fully transparent or matches TransparentColor,
+// or -1 if there is none
+func (q MedianCutQuantizer) TransparentEntry(p color.Palette) int {
+	tr, tg, tb, ta := q.transparentColor().RGBA()
+	for i := len(p) - 1; i >= 0; i-- {
+		if r, g, b, a := p[i].RGBA(); a == 0 || (r == tr && g == tg && b == tb && a == ta) {
+			return i
+		}
+	}
+	return -1
+}
+
+// target returns the number of opaque colors to add to p and whether a transparent entry should follow them
+func (q MedianCutQuantizer) target(p color.Palette) (numColors int, addTransparent bool) {
+	numColors = cap(p) - len(p)
+	if q.MaxColors > 0 {
+		numColors = q.MaxColors
+	}
+	if numColors > 0 && q.Align != NoAlignment {
+		aligned := 1 << uint(BitDepth(numColors))
+		if q.Align == AlignDown && aligned > numColors {
+			aligned /= 2
+		}
+		numColors = aligned
+	}
+	addTransparent = q.AddTransparent
+	if addTransparent {
+		if q.TransparentEntry(p) >= 0 {
+			addTransparent = false
+		}
 		if addTransparent {
 			numColors--
 		}
 	}
-	buckets := bucketize(colors, numColors)
+	return
+}
+
+// quantizeSlice expands the provided bucket and then palettizes the result
+func (q MedianCutQuantizer) quantizeSlice(p color.Palette, colors []colorPriority) color.Palette {
+	numColors, addTransparent := q.target(p)
+	if len(q.ReservedColors) > 0 {
+		var added int
+		p, added = q.reserve(p)
+		if numColors -= added; numColors < 0 {
+			numColors = 0
+		}
+		colors = cover(colors, q.ReservedColors, 0)
+	}
+	if q.SnapHistogram && q.Precision != FullPrecision {
+		for i := range colors {
+			colors[i].RGBA = q.Precision.snap(colors[i].RGBA)
+		}
+	}
+	if q.CoverRadius > 0 && len(p) > 0 {
+		colors = cover(colors, p, q.CoverRadius)
+	}
+	if q.ComplementRadius > 0 && len(p) > 0 {
+		colors = complement(colors, p, q.ComplementRadius)
+	}
+	if q.ColorSpace != RGB {
+		for i := range colors {
+			colors[i].RGBA = q.ColorSpace.encode(colors[i].RGBA)
+		}
+	}
+	buckets := q.bucketize(colors, numColors)
+	start := len(p)
 	p = q.palettize(p, buckets)
+	if q.KMeansIterations > 0 && !q.QuantizeAlpha {
+		centers := make([]center, 0, len(p)-start)
+		for _, c := range p[start:] {
+			centers = append(centers, centerOf(c.(color.RGBA)))
+		}
+		for i, c := range kmeans(colors, centers, Convergence{MaxIterations: q.KMeansIterations}) {
+			p[start+i] = c.rgba()
+		}
+	}
+	if q.ColorSpace != RGB {
+		for i := start; i < len(p); i++ {
+			p[i] = q.ColorSpace.decode(p[i].(color.RGBA))
+		}
+	}
+	if q.StraightAlpha && q.QuantizeAlpha {
+		for i := start; i < len(p); i++ {
+			p[i] = premultiply(p[i].(color.RGBA))
+		}
+	}
+	if q.FillUnused {
+		p = fillUnused(p, numColors-len(buckets))
+	}
+	if q.Precision != FullPrecision {
+		p = snapPalette(p, start, q.Precision)
+	}
 	if addTransparent {
-		p = append(p, color.RGBA{0, 0, 0, 0})
+		p = q.withTransparent(p)
 	}
 	return p
 }
 
+// nrgba16 returns the premultiplied 16-bit channels of an NRGBA pixel, matching color.NRGBA.RGBA
+func nrgba16(m *image.NRGBA, x int, y int) (r, g, b, a uint32) {
+	ci := m.PixOffset(x, y)
+	a = uint32(m.Pix[ci+3]) * 0x101
+	r = uint32(m.Pix[ci+0]) * 0x101 * a / 0xffff
+	g = uint32(m.Pix[ci+1]) * 0x101 * a / 0xffff
+	b = uint32(m.Pix[ci+2]) * 0x101 * a / 0xffff
+	return
+}
+
+// colorAt returns the premultiplied 8-bit color of a pixel. YCbCr images yield raw Y'CbCr values in R, G and B.
 func colorAt(m image.Image, x int, y int) color.RGBA {
 	switch i := m.(type) {
 	case *image.YCbCr:
@@ -139,59 +692,374 @@ func colorAt(m image.Image, x int, y int) color.RGBA {
 	case *image.RGBA:
 		ci := i.PixOffset(x, y)
 		return color.RGBA{i.Pix[ci+0], i.Pix[ci+1], i.Pix[ci+2], i.Pix[ci+3]}
+	case *image.RGBA64:
+		ci := i.PixOffset(x, y)
+		return color.RGBA{i.Pix[ci+0], i.Pix[ci+2], i.Pix[ci+4], i.Pix[ci+6]}
+	case *image.NRGBA:
+		r, g, b, a := nrgba16(i, x, y)
+		return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	case *image.Gray:
+		v := i.Pix[i.PixOffset(x, y)]
+		return color.RGBA{v, v, v, 255}
+	case *image.NRGBA64:
+		ci := i.PixOffset(x, y)
+		a := uint32(i.Pix[ci+6])<<8 | uint32(i.Pix[ci+7])
+		r := (uint32(i.Pix[ci+0])<<8 | uint32(i.Pix[ci+1])) * a / 0xffff
+		g := (uint32(i.Pix[ci+2])<<8 | uint32(i.Pix[ci+3])) * a / 0xffff
+		b := (uint32(i.Pix[ci+4])<<8 | uint32(i.Pix[ci+5])) * a / 0xffff
+		return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
 	default:
 		return color.RGBAModel.Convert(i.At(x, y)).(color.RGBA)
 	}
 }
 
+// round16 rounds a 16-bit channel to the nearest 8-bit value
+func round16(v uint32) uint8 {
+	return uint8((v*255 + 0x7fff) / 0xffff)
+}
+
+// colorAtNearest behaves like colorAt, but rounds channels deeper than 8 bits to the nearest value
+func colorAtNearest(m image.Image, x int, y int) color.RGBA {
+	switch i := m.(type) {
+	case *image.YCbCr, *image.RGBA, *image.Gray:
+		return colorAt(m, x, y)
+	case *image.NRGBA:
+		r, g, b, a := nrgba16(i, x, y)
+		return color.RGBA{round16(r), round16(g), round16(b), round16(a)}
+	case *image.RGBA64:
+		ci := i.PixOffset(x, y)
+		return color.RGBA{
+			round16(uint32(i.Pix[ci+0])<<8 | uint32(i.Pix[ci+1])),
+			round16(uint32(i.Pix[ci+2])<<8 | uint32(i.Pix[ci+3])),
+			round16(uint32(i.Pix[ci+4])<<8 | uint32(i.Pix[ci+5])),
+			round16(uint32(i.Pix[ci+6])<<8 | uint32(i.Pix[ci+7])),
+		}
+	case *image.NRGBA64:
+		ci := i.PixOffset(x, y)
+		a := uint32(i.Pix[ci+6])<<8 | uint32(i.Pix[ci+7])
+		return color.RGBA{
+			round16((uint32(i.Pix[ci+0])<<8 | uint32(i.Pix[ci+1])) * a / 0xffff),
+			round16((uint32(i.Pix[ci+2])<<8 | uint32(i.Pix[ci+3])) * a / 0xffff),
+			round16((uint32(i.Pix[ci+4])<<8 | uint32(i.Pix[ci+5])) * a / 0xffff),
+			round16(a),
+		}
+	default:
+		r, g, b, a := i.At(x, y).RGBA()
+		return color.RGBA{round16(r), round16(g), round16(b), round16(a)}
+	}
+}
+
+// outlierDistance is the squared RGB distance within which a neighboring pixel supports a color
+const outlierDistance = 3 * 24 * 24
+
+// support returns the number of pixels among the 8 neighbors of a pixel with a similar color
+func support(m image.Image, x int, y int) int {
+	bounds := m.Bounds()
+	c := rgbaAt(m, x, y)
+	n := 0
+	for ny := y - 1; ny <= y+1; ny++ {
+		for nx := x - 1; nx <= x+1; nx++ {
+			if (nx != x || ny != y) && image.Pt(nx, ny).In(bounds) && sqDiff(c, rgbaAt(m, nx, ny)) <= outlierDistance {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// salientChroma is the spread between the largest and smallest RGB channel at which a color counts as saturated
+const salientChroma = 96
+
+// salientSupport is the number of similar neighbors a saturated pixel needs to be part of a coherent region
+const salientSupport = 5
+
+// salient returns whether a pixel is highly saturated and inside a region of similar colors
+func salient(m image.Image, x int, y int) bool {
+	c := rgbaAt(m, x, y)
+	hi, lo := c.R, c.R
+	for _, v := range [2]uint8{c.G, c.B} {
+		if v > hi {
+			hi = v
+		}
+		if v < lo {
+			lo = v
+		}
+	}
+	return hi-lo >= salientChroma && support(m, x, y) >= salientSupport
+}
+
+// toneWeight returns the priority multiplier for a color under ToneBias, where raw colors hold Y'CbCr values
+func toneWeight(c color.RGBA, raw bool, bias float64) uint32 {
+	l := c.R
+	if !raw {
+		l, _, _ = color.RGBToYCbCr(c.R, c.G, c.B)
+	}
+	t := float64(l) / 255
+	if bias < 0 {
+		t, bias = 1-t, -bias
+	}
+	return uint32(16 * (1 + 3*math.Min(bias, 1)*t))
+}
+
+// reduceChroma truncates the chroma of a color to the given number of bits, where raw colors hold Y'CbCr values
+func reduceChroma(c color.RGBA, raw bool, bits int) color.RGBA {
+	mask := uint8(0xff << uint(8-bits))
+	if raw {
+		c.G &= mask
+		c.B &= mask
+		return c
+	}
+	y, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+	c.R, c.G, c.B = color.YCbCrToRGB(y, cb&mask, cr&mask)
+	return c
+}
+
+// grayscale collapses a color to its luma, where raw colors hold Y'CbCr values and stay in that form
+func grayscale(c color.RGBA, raw bool, w LumaWeights) color.RGBA {
+	if raw {
+		if w == Rec601 {
+			return color.RGBA{c.R, 128, 128, c.A}
+		}
+		r, g, b := color.YCbCrToRGB(c.R, c.G, c.B)
+		return color.RGBA{w.luma(color.RGBA{r, g, b, 255}), 128, 128, c.A}
+	}
+	l := w.luma(c)
+	return color.RGBA{l, l, l, c.A}
+}
+
+// accumulate adds the pixel at (x, y) to a sparse bucket with its priority, applying the histogram options. Pixels of
+// YCbCr images, marked by raw, are added as raw Y'CbCr values
+func (q MedianCutQuantizer) accumulate(sparseBucket colorBucket, m image.Image, x int, y int, raw bool) {
+	var c color.RGBA
+	if q.Rounding == Nearest {
+		c = colorAtNearest(m, x, y)
+	} else {
+		c = colorAt(m, x, y)
+	}
+	q.accumulateColor(sparseBucket, m, x, y, c, raw)
+}
+
+// accumulateColor adds c, already read from the pixel at (x, y) of m, to a sparse bucket as accumulate does
+func (q MedianCutQuantizer) accumulateColor(sparseBucket colorBucket, m image.Image, x int, y int, c color.RGBA, raw bool) {
+	priority := uint32(1)
+	if q.Weighting != nil {
+		priority = q.Weighting(m, x, y)
+	}
+	if priority != 0 && q.OutlierSupport > 0 && support(m, x, y) < q.OutlierSupport {
+		priority = 0
+	}
+	if priority == 0 {
+		return
+	}
+	if c.A < q.AlphaThreshold {
+		return
+	}
+	if c.A != 0 && c.A != 255 && (q.StraightAlpha || q.AlphaThreshold > 0 && !q.QuantizeAlpha) {
+		c = straightAt(m, x, y)
+		if !q.QuantizeAlpha {
+			c.A = 255
+		}
+	}
+	if q.SaturationBoost > 1 && salient(m, x, y) {
+		priority = mulPriority(priority, q.SaturationBoost)
+	}
+	if q.ToneBias != 0 {
+		priority = mulPriority(priority, toneWeight(c, raw, q.ToneBias))
+	}
+	if q.ChromaBits > 0 && q.ChromaBits < 8 {
+		c = reduceChroma(c, raw, q.ChromaBits)
+	}
+	if q.Grayscale != NoGrayscale {
+		c = grayscale(c, raw, q.Grayscale)
+	}
+	sparseBucket.add(c, priority)
+}
+
+// collect compacts a sparse bucket filled by accumulate in place, converting raw Y'CbCr values to RGB
+func (q MedianCutQuantizer) collect(sparseBucket colorBucket, raw bool) colorBucket {
+	bucket := sparseBucket[:0]
+	for _, p := range sparseBucket {
+		if p.p == 0 {
+			continue
+		}
+		if raw {
+			r, g, b := color.YCbCrToRGB(p.R, p.G, p.B)
+			p.RGBA = color.RGBA{r, g, b, p.A}
+		}
+		bucket = append(bucket, p)
+	}
+	if q.Deterministic {
+		bucket = bucket.canonical()
+	}
+	return bucket
+}
+
 // buildBucket creates a prioritized color slice with all the colors in the image
-func (q MedianCutQuantizer) buildBucket(m image.Image) (bucket colorBucket) {
+func (q MedianCutQuantizer) buildBucket(m image.Image) colorBucket {
 	bounds := m.Bounds()
 	size := (bounds.Max.X - bounds.Min.X) * (bounds.Max.Y - bounds.Min.Y) * 2
-	sparseBucket := bpool.getBucket(size)
+	sparseBucket := q.getBucket(size)
+	_, ycbcr := m.(*image.YCbCr)
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			priority := uint32(1)
-			if q.Weighting != nil {
-				priority = q.Weighting(m, x, y)
-			}
-			if priority != 0 {
-				c := colorAt(m, x, y)
-				index := int(c.R)<<16 | int(c.G)<<8 | int(c.B)
-				for i := 1; ; i++ {
-					p := &sparseBucket[index%size]
-					if p.p == 0 || p.RGBA == c {
-						*p = colorPriority{p.p + priority, c}
-						break
-					}
-					index += 1 + i
-				}
-			}
+			q.accumulate(sparseBucket, m, x, y, ycbcr)
 		}
 	}
-	bucket = sparseBucket[:0]
-	switch m.(type) {
-	case *image.YCbCr:
-		for _, p := range sparseBucket {
-			if p.p != 0 {
-				r, g, b := color.YCbCrToRGB(p.R, p.G, p.B)
-				bucket = append(bucket, colorPriority{p.p, color.RGBA{r, g, b, p.A}})
-			}
-		}
-	default:
-		for _, p := range sparseBucket {
-			if p.p != 0 {
-				bucket = append(bucket, p)
-			}
-		}
-	}
-	return
+	return q.collect(sparseBucket, ycbcr)
+}
+
+// UniqueColors returns the number of distinct weighted colors in an image, which bounds the number of colors
+// Quantize generates from its histogram. Reserved colors, the transparent entry and FillUnused entries may be added
+// beyond it, and CoverRadius, MinBucketWeight, SnapHistogram and Precision can leave fewer.
+func (q MedianCutQuantizer) UniqueColors(m image.Image) int {
+	bucket := q.buildBucket(m)
+	defer q.putBucket(bucket)
+	return len(bucket)
 }
 
 // Quantize quantizes an image to a palette and returns the palette
 func (q MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	if q.AutoTransparent {
+		q.AddTransparent, q.AlphaThreshold = DetectTransparency(m)
+	}
+	var key CacheKey
+	if q.Cache != nil {
+		numColors, _ := q.target(p)
+		key = cacheKey(p, m, numColors)
+		if colors, ok := q.Cache.Get(key); ok {
+			return append(p, colors...)
+		}
+	}
 	bucket := q.buildBucket(m)
-	defer bpool.Put(bucket)
-	return q.quantizeSlice(p, bucket)
+	defer q.putBucket(bucket)
+	start := len(p)
+	p = q.quantizeSlice(p, bucket)
+	if q.Cache != nil {
+		q.Cache.Put(key, append(color.Palette(nil), p[start:]...))
+	}
+	return p
+}
+
+// QuantizeToError behaves like Quantize, but adds only as many colors as are needed to bring the mean distance
+// between each pixel and its palette entry down to maxError, splitting the buckets with the largest error first.
+// Distances are measured in the configured ColorSpace, which for RGB is the Euclidean distance between 8-bit
+// channels. The palette capacity, or MaxColors, still bounds the number of colors added. FillUnused and Cache are
+// ignored.
+func (q MedianCutQuantizer) QuantizeToError(p color.Palette, m image.Image, maxError float64) color.Palette {
+	q.maxError = math.Max(maxError, math.SmallestNonzeroFloat64)
+	q.FillUnused = false
+	q.Cache = nil
+	return q.Quantize(p, m)
+}
+
+// binaryAlpha is an image whose pixels with alpha below a threshold are fully transparent and the rest are opaque
+type binaryAlpha struct {
+	image.Image
+	threshold uint8
+}
+
+func (m binaryAlpha) At(x, y int) color.Color {
+	c := rgbaAt(m.Image, x, y)
+	if c.A < m.threshold {
+		return color.RGBA{}
+	}
+	return unpremultiply(c)
+}
+
+// grayView is an image seen through its luma
+type grayView struct {
+	image.Image
+	weights LumaWeights
+}
+
+func (m grayView) At(x, y int) color.Color {
+	return grayscale(rgbaAt(m.Image, x, y), false, m.weights)
+}
+
+// Paletted quantizes m to at most numColors colors, including any transparent entry, and returns it drawn onto a new
+// paletted image with d. If d is nil each pixel is mapped to its nearest entry, matching every distinct color
+// against the palette only once. When a transparent entry is added, pixels with alpha below AlphaThreshold, or fully
+// transparent pixels, are mapped to it and error diffusion drawers neither diffuse error into nor out of them. Unless
+// QuantizeAlpha is set, pixels at or above a nonzero AlphaThreshold are drawn as opaque. numColors and MaxColors are
+// clamped to 256, the most entries a paletted image can index, and AlignUp never rounds past it.
+func (q MedianCutQuantizer) Paletted(m image.Image, numColors int, d draw.Drawer) *image.Paletted {
+	if numColors > 256 {
+		numColors = 256
+	}
+	if q.MaxColors > 256 {
+		q.MaxColors = 256
+	}
+	if q.AutoTransparent {
+		q.AddTransparent, q.AlphaThreshold = DetectTransparency(m)
+		q.AutoTransparent = false
+	}
+	b := m.Bounds()
+	out := image.NewPaletted(b, q.Quantize(make(color.Palette, 0, numColors), m))
+	if len(out.Palette) == 0 {
+		return out
+	}
+	threshold, transparent := uint8(0), -1
+	if q.AddTransparent {
+		threshold, transparent = q.AlphaThreshold, q.TransparentEntry(out.Palette)
+		if threshold == 0 {
+			threshold = 1
+		}
+	}
+	src := m
+	if q.Grayscale != NoGrayscale {
+		src = grayView{src, q.Grayscale}
+	}
+	if q.AlphaThreshold > 0 && !q.QuantizeAlpha {
+		src = binaryAlpha{src, q.AlphaThreshold}
+	}
+	if d != nil {
+		d.Draw(out, b, src, b.Min)
+	}
+	var lut *InverseColormap
+	if q.InverseColormap {
+		lut = NewInverseColormap(out.Palette)
+	}
+	var perceptual okLabPalette
+	if q.ColorSpace == OKLab && q.Distance == nil {
+		perceptual = newOKLabPalette(out.Palette)
+	}
+	var entries []color.RGBA
+	var indices []int
+	if q.Distance != nil {
+		entries, indices = opaqueEntries(out.Palette)
+	}
+	cache := make(map[color.RGBA]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgbaAt(src, x, y)
+			switch {
+			case transparent >= 0 && c.A < threshold:
+				out.Pix[out.PixOffset(x, y)] = uint8(transparent)
+			case d != nil:
+				// Already drawn
+			case lut != nil && c.A == 255:
+				out.Pix[out.PixOffset(x, y)] = lut.table[cell(c.R, c.G, c.B)]
+			default:
+				index, ok := cache[c]
+				if !ok {
+					i := -1
+					if c.A == 255 {
+						if len(perceptual) > 0 {
+							i = perceptual.index(c)
+						} else if n := nearestBy(entries, c, q.Distance); n >= 0 {
+							i = indices[n]
+						}
+					}
+					if i < 0 {
+						i = out.Palette.Index(c)
+					}
+					index = uint8(i)
+					cache[c] = index
+				}
+				out.Pix[out.PixOffset(x, y)] = index
+			}
+		}
+	}
+	return out
 }
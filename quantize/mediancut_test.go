@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/gif"
+	"math"
 	"os"
 	"testing"
 
@@ -22,7 +24,7 @@ func TestBuildBucket(t *testing.T) {
 		t.Fatal("Couldn't decode test file")
 	}
 
-	q := MedianCutQuantizer{Mode, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mode}
 
 	colors := q.buildBucket(i)
 	t.Logf("Naive color map contains %d elements", len(colors))
@@ -33,12 +35,12 @@ func TestBuildBucket(t *testing.T) {
 		}
 	}
 
-	q = MedianCutQuantizer{Mode, func(i image.Image, x int, y int) uint32 {
+	q = MedianCutQuantizer{Aggregation: Mode, Weighting: func(i image.Image, x int, y int) uint32 {
 		if x < 2 || y < 2 || x > i.Bounds().Max.X-2 || y > i.Bounds().Max.X-2 {
 			return 1
 		}
 		return 0
-	}, false}
+	}}
 
 	colors = q.buildBucket(i)
 	t.Logf("Color map contains %d elements", len(colors))
@@ -69,11 +71,11 @@ func TestQuantize(t *testing.T) {
 	if err != nil {
 		t.Fatal("Couldn't decode test file")
 	}
-	q := MedianCutQuantizer{Mean, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mean}
 	p := q.Quantize(make([]color.Color, 0, 256), i)
 	t.Logf("Created palette with %d colors", len(p))
 
-	q = MedianCutQuantizer{Mean, nil, false}
+	q = MedianCutQuantizer{Aggregation: Mean}
 	p2 := q.Quantize(make([]color.Color, 0, 256), i)
 
 	if len(p) != len(p2) {
@@ -86,15 +88,15 @@ func TestQuantize(t *testing.T) {
 		}
 	}
 
-	q = MedianCutQuantizer{Mode, nil, false}
+	q = MedianCutQuantizer{Aggregation: Mode}
 	p = q.Quantize(make([]color.Color, 0, 256), i)
 	t.Logf("Created palette with %d colors", len(p))
 
-	q = MedianCutQuantizer{Mean, nil, true}
+	q = MedianCutQuantizer{Aggregation: Mean, AddTransparent: true}
 	p = q.Quantize(color.Palette{color.RGBA{0, 0, 0, 0}}, i)
 	t.Logf("Created palette with %d colors", len(p))
 
-	q = MedianCutQuantizer{Mean, nil, true}
+	q = MedianCutQuantizer{Aggregation: Mean, AddTransparent: true}
 	p = q.Quantize(make([]color.Color, 0, 256), i)
 	t.Logf("Created palette with %d colors", len(p))
 }
@@ -108,7 +110,7 @@ func BenchmarkQuantize(b *testing.B) {
 	if err != nil {
 		b.Fatal("Couldn't decode test file")
 	}
-	q := MedianCutQuantizer{Mean, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mean}
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -118,7 +120,7 @@ func BenchmarkQuantize(b *testing.B) {
 
 func TestRGBAQuantize(t *testing.T) {
 	i := image.NewRGBA(image.Rect(0, 0, 1, 1))
-	q := MedianCutQuantizer{Mean, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mean}
 	p := q.Quantize(make([]color.Color, 0, 256), i)
 	t.Logf("Created palette with %d colors", len(p))
 }
@@ -133,7 +135,7 @@ func TestOverQuantize(t *testing.T) {
 	if err != nil {
 		t.Fatal("Couldn't decode test file")
 	}
-	q := MedianCutQuantizer{Mean, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mean}
 	p := q.Quantize(make([]color.Color, 0, 256), i)
 	t.Logf("Created palette with %d colors", len(p))
 }
@@ -141,7 +143,7 @@ func TestOverQuantize(t *testing.T) {
 func TestEmptyQuantize(t *testing.T) {
 	i := image.NewNRGBA(image.Rect(0, 0, 0, 0))
 
-	q := MedianCutQuantizer{Mean, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mean}
 	p := q.Quantize(make([]color.Color, 0, 256), i)
 	if len(p) != 0 {
 		t.Fatal("Quantizer returned colors for empty image")
@@ -159,7 +161,7 @@ func TestGif(t *testing.T) {
 		t.Fatal("Couldn't decode test file")
 	}
 
-	q := MedianCutQuantizer{Mode, nil, false}
+	q := MedianCutQuantizer{Aggregation: Mode}
 	f, err := os.Create("test_output.gif")
 	if err != nil {
 		t.Fatal("Couldn't open output file")
@@ -171,3 +173,620 @@ func TestGif(t *testing.T) {
 
 	gif.Encode(w, i, &options)
 }
+
+func openTestImage(t testing.TB, name string) image.Image {
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal("Couldn't open test file")
+	}
+	defer file.Close()
+	i, _, err := image.Decode(file)
+	if err != nil {
+		t.Fatal("Couldn't decode test file")
+	}
+	return i
+}
+
+func TestFillUnused(t *testing.T) {
+	i := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	i.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	i.Set(1, 0, color.RGBA{255, 255, 255, 255})
+
+	q := MedianCutQuantizer{}
+	p := q.Quantize(make([]color.Color, 0, 8), i)
+	if len(p) != 2 {
+		t.Fatalf("Expected 2 colors without filling, got %d", len(p))
+	}
+
+	q = MedianCutQuantizer{FillUnused: true}
+	p = q.Quantize(make([]color.Color, 0, 8), i)
+	if len(p) != 8 {
+		t.Fatalf("Expected 8 colors with filling, got %d", len(p))
+	}
+	seen := make(map[color.Color]bool)
+	for _, c := range p {
+		if seen[c] {
+			t.Fatal("Filled palette contains duplicates")
+		}
+		seen[c] = true
+	}
+
+	q = MedianCutQuantizer{FillUnused: true, AddTransparent: true}
+	p = q.Quantize(make([]color.Color, 0, 8), i)
+	if len(p) != 8 {
+		t.Fatalf("Expected 8 colors with filling and transparency, got %d", len(p))
+	}
+	if _, _, _, a := p[7].RGBA(); a != 0 {
+		t.Fatal("Transparent entry was not last")
+	}
+}
+
+func TestMaxColors(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{MaxColors: 64}
+	p := q.Quantize(make([]color.Color, 0, 256), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+
+	q = MedianCutQuantizer{MaxColors: 64, AddTransparent: true}
+	p = q.Quantize(make([]color.Color, 0, 256), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors including transparency, got %d", len(p))
+	}
+}
+
+func TestComplementRadius(t *testing.T) {
+	i := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	i.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	i.Set(1, 0, color.RGBA{2, 2, 2, 255})
+	i.Set(2, 0, color.RGBA{255, 255, 255, 255})
+
+	q := MedianCutQuantizer{ComplementRadius: 32}
+	p := q.Quantize(append(make([]color.Color, 0, 3), color.RGBA{0, 0, 0, 255}), i)
+	for _, c := range p[1:] {
+		if r, _, _, _ := c.RGBA(); r>>8 < 32 {
+			t.Fatalf("Quantizer produced %v, a near-duplicate of an existing entry", c)
+		}
+	}
+}
+
+func TestCoverRadius(t *testing.T) {
+	i := image.NewRGBA(image.Rect(0, 0, 16, 2))
+	for x := 0; x < 16; x++ {
+		i.Set(x, 0, color.RGBA{uint8(x), uint8(x), uint8(x), 255}) // Dark shades the seed entry covers
+	}
+	bright := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	for x := 0; x < 16; x++ {
+		i.Set(x, 1, bright[x%3])
+	}
+
+	q := MedianCutQuantizer{CoverRadius: 40}
+	p := q.Quantize(append(make([]color.Color, 0, 4), color.RGBA{0, 0, 0, 255}), i)
+	if len(p) != 4 {
+		t.Fatalf("Expected 4 colors, got %d", len(p))
+	}
+	for _, c := range p[1:] {
+		found := false
+		for _, b := range bright {
+			found = found || c == b
+		}
+		if !found {
+			t.Fatalf("Expected new entries to cover only the bright colors, got %v", p)
+		}
+	}
+}
+
+func TestReservedColors(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	brand := color.RGBA{255, 0, 255, 255}
+	q := MedianCutQuantizer{Aggregation: Mean, ReservedColors: []color.Color{brand, color.Black, color.White}}
+	p := q.Quantize(make([]color.Color, 0, 16), i)
+	if len(p) != 16 {
+		t.Fatalf("Expected 16 colors, got %d", len(p))
+	}
+	for _, r := range q.ReservedColors {
+		found := 0
+		for _, c := range p {
+			if color.RGBAModel.Convert(c) == color.RGBAModel.Convert(r) {
+				found++
+			}
+		}
+		if found != 1 {
+			t.Fatalf("Expected reserved color %v once in the palette, found it %d times", r, found)
+		}
+	}
+
+	// Reserved colors already in the palette aren't repeated
+	p = q.Quantize(append(make([]color.Color, 0, 16), color.RGBA{0, 0, 0, 255}), i)
+	if len(p) != 16 || p[1] != brand || p[2] != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("Expected the missing reserved colors after the seed, got %v", p[:3])
+	}
+}
+
+func TestGrayscale(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	nrgba := image.NewNRGBA(i.Bounds())
+	draw.Draw(nrgba, nrgba.Rect, i, i.Bounds().Min, draw.Src)
+	for _, m := range []image.Image{i, nrgba} {
+		for _, w := range []LumaWeights{Rec601, Rec709} {
+			for _, agg := range []AggregationType{Mode, Mean} {
+				q := MedianCutQuantizer{Aggregation: agg, Grayscale: w, KMeansIterations: 1}
+				p := q.Quantize(make(color.Palette, 0, 8), m)
+				if len(p) != 8 {
+					t.Fatalf("Expected 8 grays, got %d", len(p))
+				}
+				for _, e := range p {
+					if c := e.(color.RGBA); c.R != c.G || c.G != c.B {
+						t.Fatalf("Expected a gray palette, got %v", p)
+					}
+				}
+			}
+		}
+	}
+
+	green := image.NewUniform(color.RGBA{0, 255, 0, 255})
+	m := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(m, m.Rect, green, image.Point{}, draw.Src)
+	for w, want := range map[LumaWeights]uint8{Rec601: 150, Rec709: 182} {
+		out := MedianCutQuantizer{Grayscale: w}.Paletted(m, 4, FloydSteinberg{})
+		if c := out.Palette[out.ColorIndexAt(0, 0)].(color.RGBA); c != (color.RGBA{want, want, want, 255}) {
+			t.Fatalf("Expected pure green to become gray %d, got %v", want, c)
+		}
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	for _, precision := range []Precision{RGB565, RGB555} {
+		for _, snapHistogram := range []bool{false, true} {
+			q := MedianCutQuantizer{Aggregation: Mean, Precision: precision, SnapHistogram: snapHistogram, AddTransparent: true}
+			p := q.Quantize(make(color.Palette, 0, 256), i)
+			t.Logf("Created %d colors with precision %d and histogram snapping %v", len(p), precision, snapHistogram)
+			seen := make(map[color.Color]bool)
+			for _, e := range p[:len(p)-1] {
+				c := e.(color.RGBA)
+				if precision.snap(c) != c {
+					t.Fatalf("Expected entries on the %d grid, got %v", precision, c)
+				}
+				if seen[c] {
+					t.Fatalf("Expected unique entries, got %v twice", c)
+				}
+				seen[c] = true
+			}
+			if q.TransparentEntry(p) != len(p)-1 {
+				t.Fatal("Expected the transparent entry to be kept")
+			}
+		}
+	}
+	if c := RGB565.snap(color.RGBA{255, 130, 3, 255}); c != (color.RGBA{255, 130, 0, 255}) {
+		t.Fatalf("Unexpected RGB565 rounding %v", c)
+	}
+}
+
+func TestWeightedMedianPartition(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{Aggregation: Mean, Partition: WeightedMedian}
+	p := q.Quantize(make([]color.Color, 0, 256), i)
+	if len(p) != 256 {
+		t.Fatalf("Expected 256 colors, got %d", len(p))
+	}
+	t.Logf("Weighted median error %f", Quality(p, i).MeanError)
+}
+
+func TestUniqueColors(t *testing.T) {
+	i := openTestImage(t, "test_image2.gif")
+	q := MedianCutQuantizer{}
+	n := q.UniqueColors(i)
+	p := q.Quantize(make([]color.Color, 0, 256), i)
+	expected := n
+	if expected > 256 {
+		expected = 256
+	}
+	if len(p) != expected {
+		t.Fatalf("Palette has %d colors but image has %d unique colors", len(p), n)
+	}
+}
+
+func TestTransparentColor(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	magenta := color.RGBA{255, 0, 255, 255}
+	q := MedianCutQuantizer{AddTransparent: true, TransparentColor: magenta}
+	p := q.Quantize(make([]color.Color, 0, 16), i)
+	index := q.TransparentEntry(p)
+	if index != len(p)-1 || p[index] != magenta {
+		t.Fatalf("Expected magenta transparent entry at %d, got index %d", len(p)-1, index)
+	}
+
+	p = q.Quantize(append(make([]color.Color, 0, 16), magenta), i)
+	if q.TransparentEntry(p) != 0 || len(p) != 16 {
+		t.Fatal("Existing transparent entry was not reused")
+	}
+
+	q = MedianCutQuantizer{}
+	if q.TransparentEntry(color.Palette{color.RGBA{1, 2, 3, 255}}) != -1 {
+		t.Fatal("Opaque palette reported a transparent entry")
+	}
+}
+
+func TestColorAt64(t *testing.T) {
+	rgba := image.NewRGBA64(image.Rect(0, 0, 4, 4))
+	nrgba := image.NewNRGBA64(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			v := uint16(x*0x3fff + y*0x1234)
+			rgba.SetRGBA64(x, y, color.RGBA64{v / 2, v / 3, v / 4, v})
+			nrgba.SetNRGBA64(x, y, color.NRGBA64{v, 0xffff - v, v / 5, v})
+		}
+	}
+	for _, m := range []image.Image{rgba, nrgba} {
+		for x := 0; x < 4; x++ {
+			for y := 0; y < 4; y++ {
+				if c := colorAt(m, x, y); c != color.RGBAModel.Convert(m.At(x, y)) {
+					t.Fatalf("Fast path returned %v, expected %v", c, color.RGBAModel.Convert(m.At(x, y)))
+				}
+				r, g, b, a := m.At(x, y).RGBA()
+				expected := color.RGBA{round16(r), round16(g), round16(b), round16(a)}
+				if c := colorAtNearest(m, x, y); c != expected {
+					t.Fatalf("Rounded fast path returned %v, expected %v", c, expected)
+				}
+			}
+		}
+	}
+	if round16(0x7f7f) != 0x7f || round16(0x10ff) != 0x11 || round16(0xffff) != 0xff {
+		t.Fatal("round16 doesn't round to nearest")
+	}
+}
+
+func TestAlign(t *testing.T) {
+	for n, bits := range map[int]int{0: 1, 1: 1, 2: 1, 3: 2, 16: 4, 17: 5, 256: 8} {
+		if BitDepth(n) != bits {
+			t.Fatalf("Expected %d colors to need %d bits, got %d", n, bits, BitDepth(n))
+		}
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{MaxColors: 100, Align: AlignDown}
+	if p := q.Quantize(make([]color.Color, 0, 256), i); len(p) != 64 {
+		t.Fatalf("Expected 64 colors aligning down, got %d", len(p))
+	}
+	q = MedianCutQuantizer{MaxColors: 100, Align: AlignUp, AddTransparent: true}
+	if p := q.Quantize(make([]color.Color, 0, 100), i); len(p) != 128 {
+		t.Fatalf("Expected 128 colors aligning up, got %d", len(p))
+	}
+	q = MedianCutQuantizer{Align: AlignDown}
+	if p := q.Quantize(make([]color.Color, 0, 16), i); len(p) != 16 {
+		t.Fatalf("Expected aligned capacity to be unchanged, got %d", len(p))
+	}
+}
+
+func TestOutlierSupport(t *testing.T) {
+	// A gray field with a few isolated hot pixels
+	m := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			m.Set(x, y, color.RGBA{128, 128, 128, 255})
+		}
+	}
+	m.Set(3, 3, color.RGBA{255, 0, 0, 255})
+	m.Set(10, 12, color.RGBA{0, 255, 0, 255})
+	if n := (MedianCutQuantizer{}).UniqueColors(m); n != 3 {
+		t.Fatalf("Expected 3 colors without outlier rejection, got %d", n)
+	}
+	q := MedianCutQuantizer{OutlierSupport: 2}
+	if n := q.UniqueColors(m); n != 1 {
+		t.Fatalf("Expected hot pixels to be rejected, got %d colors", n)
+	}
+	p := q.Quantize(make(color.Palette, 0, 4), m)
+	if len(p) != 1 || p[0] != (color.RGBA{128, 128, 128, 255}) {
+		t.Fatalf("Expected only the field color, got %v", p)
+	}
+}
+
+func TestChromaBits(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	full := MedianCutQuantizer{}.UniqueColors(i)
+	q := MedianCutQuantizer{ChromaBits: 5}
+	reduced := q.UniqueColors(i)
+	if reduced >= full {
+		t.Fatalf("Expected fewer than %d colors with reduced chroma, got %d", full, reduced)
+	}
+	t.Logf("Reduced chroma histogram from %d to %d colors", full, reduced)
+	if p := q.Quantize(make(color.Palette, 0, 64), i); len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+
+	c := reduceChroma(color.RGBA{200, 100, 50, 255}, false, 4)
+	y, _, _ := color.RGBToYCbCr(200, 100, 50)
+	if ry, _, _ := color.RGBToYCbCr(c.R, c.G, c.B); ry < y-2 || ry > y+2 {
+		t.Fatalf("Expected luma near %d to be kept, got %d", y, ry)
+	}
+}
+
+func TestSaturationBoost(t *testing.T) {
+	// A small red logo on a large field of gray shades
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			m.Set(x, y, color.RGBA{uint8(64 + x), uint8(64 + x), uint8(64 + y), 255})
+		}
+	}
+	logo := color.RGBA{220, 20, 30, 255}
+	for y := 10; y < 14; y++ {
+		for x := 10; x < 14; x++ {
+			m.Set(x, y, logo)
+		}
+	}
+	hasLogo := func(p color.Palette) bool {
+		for _, c := range p {
+			if c == logo {
+				return true
+			}
+		}
+		return false
+	}
+	q := MedianCutQuantizer{Aggregation: Mean}
+	if hasLogo(q.Quantize(make(color.Palette, 0, 4), m)) {
+		t.Fatal("Expected the logo to be averaged away without boosting")
+	}
+	q.SaturationBoost = 1000
+	if !hasLogo(q.Quantize(make(color.Palette, 0, 4), m)) {
+		t.Fatal("Expected a boosted logo to keep its exact color")
+	}
+}
+
+func TestMinChroma(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, color.RGBA{200, 40, 40, 255})
+	m.Set(1, 0, color.RGBA{128, 128, 128, 255})
+	q := MedianCutQuantizer{Aggregation: Mean}
+	plain, _ := chroma(q.Quantize(make(color.Palette, 0, 1), m)[0].(color.RGBA))
+	q.MinChroma = 0.9
+	boosted, _ := chroma(q.Quantize(make(color.Palette, 0, 1), m)[0].(color.RGBA))
+	most, _ := chroma(color.RGBA{200, 40, 40, 255})
+	if boosted <= plain || boosted < most*0.9-1 {
+		t.Fatalf("Expected chroma of at least %f, got %f (plain mean %f)", most*0.9, boosted, plain)
+	}
+}
+
+func TestToneBias(t *testing.T) {
+	m := grayRamp(0, 255)
+	darkest := func(bias float64) int {
+		q := MedianCutQuantizer{Aggregation: Mean, ToneBias: bias}
+		n := 0
+		for _, c := range q.Quantize(make(color.Palette, 0, 8), m) {
+			if c.(color.RGBA).R < 128 {
+				n++
+			}
+		}
+		return n
+	}
+	if shadows, highlights := darkest(-1), darkest(1); shadows <= highlights {
+		t.Fatalf("Expected more dark entries with a shadow bias, got %d and %d", shadows, highlights)
+	}
+	if w := toneWeight(gray(0), false, -1); w != 64 {
+		t.Fatalf("Expected black to weigh 64 under full shadow bias, got %d", w)
+	}
+}
+
+func TestKMeansIterations(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{Aggregation: Mean}
+	plain := Quality(q.Quantize(make(color.Palette, 0, 32), i), i).MeanError
+	q.KMeansIterations = 4
+	p := q.Quantize(make(color.Palette, 0, 32), i)
+	if len(p) != 32 {
+		t.Fatalf("Expected 32 colors, got %d", len(p))
+	}
+	if refined := Quality(p, i).MeanError; refined >= plain {
+		t.Fatalf("Expected refinement to lower the error below %f, got %f", plain, refined)
+	}
+}
+
+func TestSplitStrategy(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	for _, split := range []SplitStrategy{LongestAxis, LargestVariance, LargestProduct} {
+		q := MedianCutQuantizer{Aggregation: Mean, Split: split}
+		p := q.Quantize(make(color.Palette, 0, 32), i)
+		if len(p) != 32 {
+			t.Fatalf("Expected 32 colors with strategy %d, got %d", split, len(p))
+		}
+		t.Logf("Strategy %d mean error %f", split, Quality(p, i).MeanError)
+	}
+	plain := Quality(MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 32), i), i).MeanError
+	variance := Quality(MedianCutQuantizer{Aggregation: Mean, Split: LargestVariance}.Quantize(make(color.Palette, 0, 32), i), i).MeanError
+	if variance >= plain {
+		t.Fatalf("Expected variance splitting to lower the error below %f, got %f", plain, variance)
+	}
+}
+
+func TestQuantizeToError(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 256, 4))
+	for x := 0; x < 256; x++ {
+		for y := 0; y < 4; y++ {
+			m.Set(x, y, color.RGBA{uint8(x), uint8(x), uint8(x), 255})
+		}
+	}
+	q := MedianCutQuantizer{Aggregation: Mean}
+	coarse := q.QuantizeToError(make(color.Palette, 0, 256), m, 16)
+	fine := q.QuantizeToError(make(color.Palette, 0, 256), m, 2)
+	if len(coarse) >= len(fine) {
+		t.Fatalf("Expected a tighter error to use more colors, got %d and %d", len(coarse), len(fine))
+	}
+	for _, c := range []struct {
+		p        color.Palette
+		maxError float64
+	}{{coarse, 16}, {fine, 2}} {
+		var sum float64
+		for x := 0; x < 256; x++ {
+			e := c.p[c.p.Index(m.At(x, 0))].(color.RGBA)
+			sum += math.Abs(float64(e.R) - float64(x))
+		}
+		// Every gray is within its bucket, so the nearest entry is at least as close as its own
+		if mean := sum / 256 * math.Sqrt(3); mean > c.maxError {
+			t.Fatalf("Expected a mean error of at most %v with %d colors, got %v", c.maxError, len(c.p), mean)
+		}
+	}
+	if p := q.QuantizeToError(make(color.Palette, 0, 4), m, 2); len(p) != 4 {
+		t.Fatalf("Expected the palette capacity to bound the colors added, got %d", len(p))
+	}
+}
+
+func TestPaletted(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{AddTransparent: true}
+	out := q.Paletted(i, 16, nil)
+	if len(out.Palette) != 16 || out.Rect != i.Bounds() {
+		t.Fatalf("Expected a 16 color image over %v, got %d colors over %v", i.Bounds(), len(out.Palette), out.Rect)
+	}
+	b := i.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 7 {
+		for x := b.Min.X; x < b.Max.X; x += 7 {
+			if want := uint8(out.Palette.Index(rgbaAt(i, x, y))); out.ColorIndexAt(x, y) != want {
+				t.Fatalf("Expected index %d at (%d, %d), got %d", want, x, y, out.ColorIndexAt(x, y))
+			}
+		}
+	}
+	dithered := q.Paletted(i, 16, FloydSteinberg{})
+	if string(dithered.Pix) == string(out.Pix) {
+		t.Fatal("Expected the drawer to be used")
+	}
+}
+
+func TestPalettedMaxColors(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			m.SetRGBA(x, y, color.RGBA{uint8(x * 2), uint8(y * 2), uint8(x + y), 255})
+		}
+	}
+	for _, q := range []MedianCutQuantizer{{}, {MaxColors: 300}, {Align: AlignUp}} {
+		out := q.Paletted(m, 300, nil)
+		if len(out.Palette) > 256 {
+			t.Fatalf("Expected at most 256 colors, got %d", len(out.Palette))
+		}
+		for y := 0; y < 128; y++ {
+			for x := 0; x < 128; x++ {
+				if want := out.Palette[out.Palette.Index(m.At(x, y))]; out.At(x, y) != want {
+					t.Fatalf("Expected %v at (%d, %d), got %v", want, x, y, out.At(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestPalettedTransparency(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			a := uint8(255)
+			if x < 16 {
+				a = uint8(x * 4) // Faint pixels fall below the threshold
+			}
+			m.Set(x, y, color.NRGBA{uint8(x * 8), uint8(y * 8), 128, a})
+		}
+	}
+	q := MedianCutQuantizer{AddTransparent: true, AlphaThreshold: 128}
+	for _, d := range []draw.Drawer{nil, FloydSteinberg{}, Riemersma{}} {
+		out := q.Paletted(m, 8, d)
+		transparent := q.TransparentEntry(out.Palette)
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				if got := out.ColorIndexAt(x, y); (x < 16) != (int(got) == transparent) {
+					t.Fatalf("Expected only faint pixels to map to the transparent entry %d, got %d at (%d, %d)", transparent, got, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestAlphaThresholdOpaque(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 32, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 32; x++ {
+			m.Set(x, y, color.NRGBA{200, 40, 40, uint8(x * 8)})
+		}
+	}
+	q := MedianCutQuantizer{AddTransparent: true, AlphaThreshold: 100}
+	for _, d := range []draw.Drawer{nil, FloydSteinberg{}} {
+		out := q.Paletted(m, 4, d)
+		transparent := q.TransparentEntry(out.Palette)
+		for x := 0; x < 32; x++ {
+			index := int(out.ColorIndexAt(x, 0))
+			if x*8 < 100 {
+				if index != transparent {
+					t.Fatalf("Expected alpha %d to map to the transparent entry, got %d", x*8, index)
+				}
+				continue
+			}
+			if c := out.Palette[index].(color.RGBA); c.A != 255 || sqDiff(c, color.RGBA{200, 40, 40, 255}) > 48 {
+				t.Fatalf("Expected alpha %d to map to an opaque entry near the straight color, got %v", x*8, c)
+			}
+		}
+	}
+}
+
+func TestQuantizeAlpha(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 64, 4))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 4; y++ {
+			m.Set(x, y, color.NRGBA{200, 40, 40, uint8(x * 4)})
+		}
+	}
+	for _, q := range []MedianCutQuantizer{
+		{QuantizeAlpha: true, Aggregation: Mean},
+		{QuantizeAlpha: true, Aggregation: Mode},
+		{QuantizeAlpha: true, Aggregation: Mean, Partition: WeightedMedian, KMeansIterations: 2},
+	} {
+		p := q.Quantize(make(color.Palette, 0, 4), m)
+		if len(p) != 4 {
+			t.Fatalf("Expected 4 colors, got %d", len(p))
+		}
+		alphas := make(map[uint8]bool)
+		for _, c := range p {
+			rgba := c.(color.RGBA)
+			if rgba.R > rgba.A {
+				t.Fatalf("Expected premultiplied entries, got %v", rgba)
+			}
+			alphas[rgba.A] = true
+		}
+		if len(alphas) != 4 {
+			t.Fatalf("Expected entries at 4 distinct alphas, got %v", p)
+		}
+	}
+	for _, c := range (MedianCutQuantizer{Aggregation: Mean}).Quantize(make(color.Palette, 0, 4), m) {
+		if c.(color.RGBA).A != 255 {
+			t.Fatalf("Expected opaque Mean entries without QuantizeAlpha, got %v", c)
+		}
+	}
+}
+
+func TestTransparentIndex(t *testing.T) {
+	i := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	i.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	i.Set(1, 0, color.RGBA{255, 255, 255, 255})
+
+	for _, index := range []int{0, 3, 100} {
+		q := MedianCutQuantizer{FillUnused: true, AddTransparent: true, AlphaThreshold: 1, PlaceTransparent: true, TransparentIndex: index}
+		p := q.Quantize(make([]color.Color, 0, 8), i)
+		expected := index
+		if expected > 7 {
+			expected = 7
+		}
+		if len(p) != 8 {
+			t.Fatalf("Expected 8 colors, got %d", len(p))
+		}
+		if got := q.TransparentEntry(p); got != expected {
+			t.Fatalf("Expected the transparent entry at %d, got %d", expected, got)
+		}
+		out := q.Paletted(i, 8, nil)
+		if got := out.ColorIndexAt(2, 0); int(got) != q.TransparentEntry(out.Palette) {
+			t.Fatalf("Expected the transparent pixel to map to the placed entry, got %d", got)
+		}
+	}
+
+	q := WuQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true, PlaceTransparent: true}}
+	if p := q.Quantize(make([]color.Color, 0, 4), i.SubImage(image.Rect(0, 0, 2, 1))); q.MedianCut.TransparentEntry(p) != 0 {
+		t.Fatalf("Expected the transparent entry first, got %v", p)
+	}
+}
@@ -0,0 +1,66 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// mergeBuckets combines the histograms of several images into a single bucket, summing the priority of shared colors
+func (q MedianCutQuantizer) mergeBuckets(buckets []colorBucket) colorBucket {
+	var total int
+	for _, b := range buckets {
+		total += len(b)
+	}
+	sparseBucket := q.getBucket(total * 2)
+	if total == 0 {
+		return sparseBucket
+	}
+	for _, b := range buckets {
+		for _, c := range b {
+			sparseBucket.add(c.RGBA, c.p)
+		}
+	}
+	merged := sparseBucket[:0]
+	for _, c := range sparseBucket {
+		if c.p != 0 {
+			merged = append(merged, c)
+		}
+	}
+	if q.Deterministic {
+		merged = merged.canonical()
+	}
+	return merged
+}
+
+// QuantizeMultiple quantizes several images, such as the frames of an animation, to a single shared palette
+func (q MedianCutQuantizer) QuantizeMultiple(p color.Palette, images []image.Image) color.Palette {
+	q.Scratch = nil // Several histograms are held at once
+	buckets := make([]colorBucket, len(images))
+	for i, m := range images {
+		buckets[i] = q.buildBucket(m)
+	}
+	bucket := q.mergeBuckets(buckets)
+	for _, b := range buckets {
+		q.putBucket(b)
+	}
+	defer q.putBucket(bucket)
+	return q.quantizeSlice(p, bucket)
+}
+
+// QuantizeMultipleUsage behaves like QuantizeMultiple, additionally returning for each image the ascending indices
+// of the palette entries its pixels map to, so encoders can emit minimal local color tables. Usage is counted by
+// mapping each pixel to its nearest entry with color.Palette.Index, which matches images drawn with draw.Src. Images
+// drawn with a dithering drawer, or by Paletted with a Distance, InverseColormap or AlphaThreshold, may use entries
+// missing from their list, so those callers should count indices from the drawn image instead
+func (q MedianCutQuantizer) QuantizeMultipleUsage(p color.Palette, images []image.Image) (color.Palette, [][]int) {
+	p = q.QuantizeMultiple(p, images)
+	usage := make([][]int, len(images))
+	for i, m := range images {
+		for index, e := range Quality(p, m).Entries {
+			if e.Pixels > 0 {
+				usage[i] = append(usage[i], index)
+			}
+		}
+	}
+	return p, usage
+}
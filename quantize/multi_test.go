@@ -0,0 +1,77 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestQuantizeMultiple(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	a.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	a.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	b := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	b.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	b.Set(1, 0, color.RGBA{0, 0, 255, 255})
+
+	q := MedianCutQuantizer{}
+	p := q.QuantizeMultiple(make([]color.Color, 0, 8), []image.Image{a, b})
+	if len(p) != 3 {
+		t.Fatalf("Expected 3 shared colors, got %d", len(p))
+	}
+
+	i := openTestImage(t, "test_image.jpg")
+	p = q.QuantizeMultiple(make([]color.Color, 0, 256), []image.Image{i, a, b})
+	t.Logf("Created palette with %d colors", len(p))
+
+	p = q.QuantizeMultiple(make([]color.Color, 0, 256), nil)
+	if len(p) != 0 {
+		t.Fatal("Quantizer returned colors for no images")
+	}
+}
+
+func TestQuantizeMultipleUsage(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	a.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	b := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	b.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	b.Set(1, 0, color.RGBA{0, 0, 255, 255})
+
+	q := MedianCutQuantizer{}
+	p, usage := q.QuantizeMultipleUsage(make([]color.Color, 0, 8), []image.Image{a, b})
+	if len(usage) != 2 {
+		t.Fatal("Usage doesn't cover every image")
+	}
+	if len(usage[0]) != 1 || len(usage[1]) != 2 {
+		t.Fatalf("Unexpected usage %v", usage)
+	}
+	if p[usage[0][0]] != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatal("First image mapped to the wrong entry")
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	m := openTestImage(t, "test_image.jpg").(*image.YCbCr)
+	bounds := m.Bounds()
+	mid := (bounds.Min.Y + bounds.Max.Y) / 2
+	top := m.SubImage(image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, mid))
+	bottom := m.SubImage(image.Rect(bounds.Min.X, mid, bounds.Max.X, bounds.Max.Y))
+
+	for _, q := range []MedianCutQuantizer{
+		{Deterministic: true},
+		{Deterministic: true, Aggregation: Mean, Partition: WeightedMedian},
+	} {
+		whole := q.Quantize(make(color.Palette, 0, 64), m)
+		for _, images := range [][]image.Image{{top, bottom}, {bottom, top}} {
+			p := q.QuantizeMultiple(make(color.Palette, 0, 64), images)
+			if len(p) != len(whole) {
+				t.Fatalf("Expected %d colors, got %d", len(whole), len(p))
+			}
+			for i := range p {
+				if p[i] != whole[i] {
+					t.Fatalf("Expected entry %d to be %v regardless of how the histogram was built, got %v", i, whole[i], p[i])
+				}
+			}
+		}
+	}
+}
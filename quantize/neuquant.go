@@ -0,0 +1,157 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// NeuQuant learning parameters, following Anthony Dekker's original implementation
+const (
+	neuCycles        = 100
+	neuRadiusDecay   = 30
+	neuMinPixels     = 1509
+	neuDefaultSample = 10
+)
+
+// neuPrimes are the strides used to visit pixels in a scattered order; one that doesn't divide the pixel count is
+// chosen so every pixel can be reached
+var neuPrimes = []int{499, 491, 487, 503}
+
+// NeuQuantQuantizer implements the go draw.Quantizer interface using Anthony Dekker's NeuQuant, a Kohonen
+// self-organizing map trained on a sample of the image's pixels, as used by many GIF encoders
+type NeuQuantQuantizer struct {
+	// Transparency and palette size options. Weighting is ignored since the network samples pixels directly
+	MedianCut MedianCutQuantizer
+	// Train on one in every SampleFactor pixels, from 1 for the best quality to 30 for the fastest. If zero, 10 is
+	// used
+	SampleFactor int
+}
+
+// neuNetwork is a one-dimensional self-organizing map of colors
+type neuNetwork struct {
+	neurons [][3]float64
+	freq    []float64
+	bias    []float64
+}
+
+func newNeuNetwork(size int) *neuNetwork {
+	n := &neuNetwork{
+		neurons: make([][3]float64, size),
+		freq:    make([]float64, size),
+		bias:    make([]float64, size),
+	}
+	for i := range n.neurons {
+		v := float64(i) * 256 / float64(size)
+		n.neurons[i] = [3]float64{v, v, v}
+		n.freq[i] = 1 / float64(size)
+	}
+	return n
+}
+
+// contest finds the neuron closest to c after biasing against frequently chosen neurons, updating their frequencies
+func (n *neuNetwork) contest(c [3]float64) int {
+	best, bestBiased := math.Inf(1), math.Inf(1)
+	bestPos, bestBiasedPos := 0, 0
+	for i, neuron := range n.neurons {
+		dist := math.Abs(neuron[0]-c[0]) + math.Abs(neuron[1]-c[1]) + math.Abs(neuron[2]-c[2])
+		if dist < best {
+			best, bestPos = dist, i
+		}
+		if biased := dist - n.bias[i]; biased < bestBiased {
+			bestBiased, bestBiasedPos = biased, i
+		}
+		f := n.freq[i] / 1024
+		n.freq[i] -= f
+		n.bias[i] += f * 1024
+	}
+	n.freq[bestPos] += 1.0 / 1024
+	n.bias[bestPos]--
+	return bestBiasedPos
+}
+
+// alter moves neuron i towards c by alpha
+func (n *neuNetwork) alter(i int, alpha float64, c [3]float64) {
+	for ch := range c {
+		n.neurons[i][ch] -= alpha * (n.neurons[i][ch] - c[ch])
+	}
+}
+
+// learn trains the network on pixels, visiting one in every factor of them
+func (n *neuNetwork) learn(pixels [][3]float64, factor int) {
+	samples := len(pixels) / factor
+	step := neuPrimes[len(neuPrimes)-1]
+	for _, p := range neuPrimes {
+		if len(pixels)%p != 0 {
+			step = p
+			break
+		}
+	}
+	delta := samples / neuCycles
+	if delta == 0 {
+		delta = 1
+	}
+	alpha, alphaDecay := 1.0, 30+float64(factor-1)/3
+	radius := float64(len(n.neurons)) / 8
+	pos := 0
+	for i := 0; i < samples; i++ {
+		c := pixels[pos]
+		j := n.contest(c)
+		n.alter(j, alpha, c)
+		if rad := int(radius); rad > 1 {
+			for k := 1; k < rad; k++ {
+				a := alpha * float64(rad*rad-k*k) / float64(rad*rad)
+				if j-k >= 0 {
+					n.alter(j-k, a, c)
+				}
+				if j+k < len(n.neurons) {
+					n.alter(j+k, a, c)
+				}
+			}
+		}
+		pos = (pos + step) % len(pixels)
+		if (i+1)%delta == 0 {
+			alpha -= alpha / alphaDecay
+			radius -= radius / neuRadiusDecay
+		}
+	}
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q NeuQuantQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	b := m.Bounds()
+	pixels := make([][3]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			if c.A < q.MedianCut.AlphaThreshold {
+				continue
+			}
+			pixels = append(pixels, [3]float64{float64(c.R), float64(c.G), float64(c.B)})
+		}
+	}
+	if numColors > 0 && len(pixels) > 0 {
+		factor := q.SampleFactor
+		if factor <= 0 {
+			factor = neuDefaultSample
+		}
+		if len(pixels) < neuMinPixels {
+			factor = 1
+		}
+		n := newNeuNetwork(numColors)
+		n.learn(pixels, factor)
+		for _, neuron := range n.neurons {
+			p = append(p, color.RGBA{
+				uint8(math.Round(math.Max(0, math.Min(255, neuron[0])))),
+				uint8(math.Round(math.Max(0, math.Min(255, neuron[1])))),
+				uint8(math.Round(math.Max(0, math.Min(255, neuron[2])))),
+				255,
+			})
+		}
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,23 @@
+package quantize
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestNeuQuantQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	for _, factor := range []int{1, 10, 30} {
+		q := NeuQuantQuantizer{SampleFactor: factor}
+		p := q.Quantize(make(color.Palette, 0, 64), i)
+		if len(p) != 64 {
+			t.Fatalf("Expected 64 colors, got %d", len(p))
+		}
+		nq := Quality(p, i).MeanError
+		mc := Quality(MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 64), i), i).MeanError
+		t.Logf("NeuQuant error %f at sample factor %d, median cut error %f", nq, factor, mc)
+		if nq > mc*2 {
+			t.Fatalf("Expected NeuQuant error %f to be comparable to median cut error %f", nq, mc)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// OrchardBoumanQuantizer implements the go draw.Quantizer interface using Orchard and Bouman's binary splitting.
+// The cluster with the largest spread along its principal axis is split by the plane through its mean perpendicular
+// to that axis, so correlated colors such as skin tones and skies are divided along their natural direction rather
+// than an RGB axis.
+type OrchardBoumanQuantizer struct {
+	// Histogram and transparency options
+	MedianCut MedianCutQuantizer
+}
+
+// obCluster is a bucket with its weighted mean and the variance along its principal axis
+type obCluster struct {
+	colors colorBucket
+	mean   [3]float64
+	axis   [3]float64
+	spread float64
+}
+
+// newCluster measures a bucket's mean and principal axis
+func newCluster(colors colorBucket) obCluster {
+	c := obCluster{colors: colors}
+	var total float64
+	var scatter [3][3]float64
+	for _, e := range colors {
+		w := float64(e.p)
+		v := [3]float64{float64(e.R), float64(e.G), float64(e.B)}
+		total += w
+		for i := range v {
+			c.mean[i] += w * v[i]
+			for j := range v {
+				scatter[i][j] += w * v[i] * v[j]
+			}
+		}
+	}
+	if total == 0 {
+		return c
+	}
+	for i := range c.mean {
+		c.mean[i] /= total
+	}
+	for i := range scatter {
+		for j := range scatter[i] {
+			scatter[i][j] -= total * c.mean[i] * c.mean[j]
+		}
+	}
+	// Find the principal eigenvector of the scatter matrix by power iteration, starting from the channel with the
+	// largest variance so the start can't be orthogonal to the scatter
+	widest := 0
+	for i := range scatter {
+		if scatter[i][i] > scatter[widest][widest] {
+			widest = i
+		}
+	}
+	c.axis = [3]float64{}
+	c.axis[widest] = 1
+	for iter := 0; iter < 32; iter++ {
+		var next [3]float64
+		for i := range scatter {
+			for j := range scatter[i] {
+				next[i] += scatter[i][j] * c.axis[j]
+			}
+		}
+		norm := math.Sqrt(next[0]*next[0] + next[1]*next[1] + next[2]*next[2])
+		if norm == 0 {
+			return c
+		}
+		for i := range next {
+			next[i] /= norm
+		}
+		c.axis = next
+		c.spread = norm
+	}
+	return c
+}
+
+// project returns a color's position along the cluster's principal axis relative to its mean
+func (c obCluster) project(e colorPriority) float64 {
+	return (float64(e.R)-c.mean[0])*c.axis[0] + (float64(e.G)-c.mean[1])*c.axis[1] + (float64(e.B)-c.mean[2])*c.axis[2]
+}
+
+// split divides the cluster by the plane through its mean perpendicular to its principal axis
+func (c obCluster) split() (obCluster, obCluster) {
+	cb := c.colors
+	sort.Slice(cb, func(i, j int) bool { return c.project(cb[i]) < c.project(cb[j]) })
+	cut := sort.Search(len(cb), func(i int) bool { return c.project(cb[i]) > 0 })
+	if cut == 0 || cut == len(cb) {
+		cut = len(cb) / 2
+	}
+	return newCluster(cb[:cut]), newCluster(cb[cut:])
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q OrchardBoumanQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	if numColors > 0 && len(bucket) > 0 {
+		clusters := []obCluster{newCluster(bucket)}
+		for len(clusters) < numColors {
+			best := -1
+			for i, c := range clusters {
+				if len(c.colors) >= 2 && (best < 0 || c.spread > clusters[best].spread) {
+					best = i
+				}
+			}
+			if best < 0 {
+				break
+			}
+			left, right := clusters[best].split()
+			clusters[best] = left
+			clusters = append(clusters, right)
+		}
+		for _, c := range clusters {
+			p = append(p, color.RGBA{
+				uint8(math.Round(c.mean[0])),
+				uint8(math.Round(c.mean[1])),
+				uint8(math.Round(c.mean[2])),
+				255,
+			})
+		}
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
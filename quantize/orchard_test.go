@@ -0,0 +1,49 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOrchardBoumanQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	p := OrchardBoumanQuantizer{}.Quantize(make(color.Palette, 0, 32), i)
+	if len(p) != 32 {
+		t.Fatalf("Expected 32 colors, got %d", len(p))
+	}
+	ob := Quality(p, i).MeanError
+	mc := Quality(MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 32), i), i).MeanError
+	t.Logf("Orchard-Bouman error %f, median cut error %f", ob, mc)
+	if ob >= mc {
+		t.Fatalf("Expected Orchard-Bouman error %f to be below median cut error %f", ob, mc)
+	}
+
+	// A diagonal gradient is split across the diagonal rather than along one channel
+	m := image.NewRGBA(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		m.SetRGBA(x, 0, color.RGBA{uint8(x), uint8(x), 0, 255})
+	}
+	p = OrchardBoumanQuantizer{}.Quantize(make(color.Palette, 0, 2), m)
+	for _, c := range p {
+		if rgba := c.(color.RGBA); rgba.R != rgba.G {
+			t.Fatalf("Expected entries on the diagonal, got %v", p)
+		}
+	}
+
+	// A gradient orthogonal to the gray axis is still split along its length
+	for x := 0; x < 256; x++ {
+		m.SetRGBA(x, 0, color.RGBA{uint8(x), uint8(255 - x), 50, 255})
+	}
+	p = OrchardBoumanQuantizer{}.Quantize(make(color.Palette, 0, 4), m)
+	if len(p) != 4 {
+		t.Fatalf("Expected 4 colors, got %d", len(p))
+	}
+	for i, a := range p {
+		for _, b := range p[i+1:] {
+			if sqDiff(a.(color.RGBA), b.(color.RGBA)) < 40*40 {
+				t.Fatalf("Expected entries spread along the red to green gradient, got %v", p)
+			}
+		}
+	}
+}
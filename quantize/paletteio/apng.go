@@ -0,0 +1,179 @@
+package paletteio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+// pngSignature starts every PNG file
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// apngDisposal maps GIF disposal methods to APNG dispose_op values
+var apngDisposal = map[byte]byte{
+	gif.DisposalNone:       0,
+	gif.DisposalBackground: 1,
+	gif.DisposalPrevious:   2,
+}
+
+// writeChunk writes a PNG chunk with its length and checksum
+func writeChunk(w io.Writer, typ string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	for _, b := range [][]byte{header[:], data, sum[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pngChunks encodes m as a PNG and returns its chunks by type, with repeated chunks such as IDAT concatenated
+func pngChunks(m image.Image) (map[string][]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return nil, err
+	}
+	chunks := make(map[string][]byte)
+	data := buf.Bytes()[len(pngSignature):]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data[:4])
+		typ := string(data[4:8])
+		chunks[typ] = append(chunks[typ], data[8:8+n]...)
+		data = data[12+n:]
+	}
+	return chunks, nil
+}
+
+// WriteAPNG writes an animation as an animated PNG, preserving each frame's placement, delay and disposal method and
+// the loop count. APNG holds a single palette, so every frame must share the palette of the first. Frames are blended
+// over the canvas, so transparent pixels show the frames beneath them as in a GIF.
+func WriteAPNG(w io.Writer, g *gif.GIF) error {
+	if len(g.Image) == 0 {
+		return errors.New("paletteio: animation has no frames")
+	}
+	p := g.Image[0].Palette
+	bounds := g.Image[0].Bounds()
+	for i, frame := range g.Image {
+		if !samePalette(frame.Palette, p) {
+			return fmt.Errorf("paletteio: frame %d doesn't share the first frame's palette", i)
+		}
+		bounds = bounds.Union(frame.Bounds())
+	}
+	width, height := g.Config.Width, g.Config.Height
+	if width == 0 || height == 0 {
+		width, height = bounds.Max.X, bounds.Max.Y
+	}
+	canvas := image.Rect(0, 0, width, height)
+
+	// The first frame is also the image shown by decoders without APNG support, so it must cover the canvas
+	first := g.Image[0]
+	if first.Rect != canvas {
+		padded := image.NewPaletted(canvas, p)
+		for i, c := range p {
+			if _, _, _, a := c.RGBA(); a == 0 {
+				for j := range padded.Pix {
+					padded.Pix[j] = uint8(i)
+				}
+				break
+			}
+		}
+		for y := first.Rect.Min.Y; y < first.Rect.Max.Y; y++ {
+			for x := first.Rect.Min.X; x < first.Rect.Max.X; x++ {
+				if (image.Point{x, y}).In(canvas) {
+					padded.SetColorIndex(x, y, first.ColorIndexAt(x, y))
+				}
+			}
+		}
+		first = padded
+	}
+
+	if _, err := io.WriteString(w, pngSignature); err != nil {
+		return err
+	}
+	var seq uint32
+	for i, frame := range g.Image {
+		if i == 0 {
+			frame = first
+		}
+		b := frame.Bounds()
+		if !b.In(canvas) {
+			return fmt.Errorf("paletteio: frame %d at %v lies outside the %dx%d canvas", i, b, width, height)
+		}
+		local := &image.Paletted{Pix: frame.Pix, Stride: frame.Stride, Rect: b.Sub(b.Min), Palette: p}
+		chunks, err := pngChunks(local)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			// GIF counts repeats, with 0 repeating forever and -1 playing once, while APNG counts plays
+			var plays uint32
+			switch {
+			case g.LoopCount < 0:
+				plays = 1
+			case g.LoopCount > 0:
+				plays = uint32(g.LoopCount + 1)
+			}
+			var actl [8]byte
+			binary.BigEndian.PutUint32(actl[:4], uint32(len(g.Image)))
+			binary.BigEndian.PutUint32(actl[4:], plays)
+			if err := writeChunk(w, "IHDR", chunks["IHDR"]); err != nil {
+				return err
+			}
+			if err := writeChunk(w, "acTL", actl[:]); err != nil {
+				return err
+			}
+			for _, typ := range []string{"PLTE", "tRNS"} {
+				if data, ok := chunks[typ]; ok {
+					if err := writeChunk(w, typ, data); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		var fctl [26]byte
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(b.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(b.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(b.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(b.Min.Y))
+		if i < len(g.Delay) {
+			binary.BigEndian.PutUint16(fctl[20:], uint16(g.Delay[i]))
+		}
+		binary.BigEndian.PutUint16(fctl[22:], 100) // Delays are in hundredths of a second
+		if i < len(g.Disposal) {
+			fctl[24] = apngDisposal[g.Disposal[i]]
+		}
+		fctl[25] = 1 // APNG_BLEND_OP_OVER
+		seq++
+		if err := writeChunk(w, "fcTL", fctl[:]); err != nil {
+			return err
+		}
+
+		if i == 0 {
+			err = writeChunk(w, "IDAT", chunks["IDAT"])
+		} else {
+			data := make([]byte, 4, 4+len(chunks["IDAT"]))
+			binary.BigEndian.PutUint32(data, seq)
+			seq++
+			err = writeChunk(w, "fdAT", append(data, chunks["IDAT"]...))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writeChunk(w, "IEND", nil)
+}
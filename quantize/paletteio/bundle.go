@@ -0,0 +1,154 @@
+package paletteio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// BundleFrame describes one frame in a frame bundle manifest
+type BundleFrame struct {
+	// The indexed PNG holding the frame, relative to the bundle directory
+	File string `json:"file"`
+	// The palette file the frame's indices refer to, relative to the bundle directory
+	Palette string `json:"palette"`
+	// The region of the canvas the frame covers
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// The delay after the frame in hundredths of a second
+	Delay int `json:"delay"`
+	// The GIF disposal method applied after the frame
+	Disposal byte `json:"disposal"`
+	// The index of the transparent entry in the frame's palette, or -1 if there is none
+	Transparent int `json:"transparent"`
+}
+
+// BundleManifest is the manifest.json document written by WriteFrameBundle
+type BundleManifest struct {
+	Width     int           `json:"width"`
+	Height    int           `json:"height"`
+	LoopCount int           `json:"loopCount"`
+	Frames    []BundleFrame `json:"frames"`
+}
+
+// samePalette returns whether two palettes hold the same entries
+func samePalette(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ar, ag, ab, aa := a[i].RGBA()
+		br, bg, bb, ba := b[i].RGBA()
+		if ar != br || ag != bg || ab != bb || aa != ba {
+			return false
+		}
+	}
+	return true
+}
+
+// firstTransparent returns the index of the first fully transparent entry of p, which image/gif encodes as the
+// transparent index, or -1 if there is none
+func firstTransparent(p color.Palette) int {
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeFile creates a file in dir and writes it with fn
+func writeFile(dir, name string, fn func(f *os.File) error) error {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteFrameBundle writes an animation to dir as indexed PNG frames, a shared palette.json, and a manifest.json
+// recording each frame's placement, delay, disposal and transparent index, for players that consume pre-quantized
+// frames. The shared palette is the GIF's global palette, or the first frame's palette if it has none; frames with
+// a different palette get their own palette file. transparent locates the transparent entry of a palette, such as
+// the TransparentEntry method of the quantizer that built it; if nil, the first fully transparent entry is used, as
+// image/gif does. The directory is created if needed.
+func WriteFrameBundle(dir string, g *gif.GIF, transparent func(color.Palette) int) error {
+	if len(g.Image) == 0 {
+		return errors.New("paletteio: animation has no frames")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if transparent == nil {
+		transparent = firstTransparent
+	}
+	shared := g.Image[0].Palette
+	if p, ok := g.Config.ColorModel.(color.Palette); ok && len(p) > 0 {
+		shared = p
+	}
+	if err := writeFile(dir, "palette.json", func(f *os.File) error {
+		return WriteJSON(f, shared, transparent(shared), nil, nil)
+	}); err != nil {
+		return err
+	}
+
+	bounds := g.Image[0].Bounds()
+	for _, frame := range g.Image[1:] {
+		bounds = bounds.Union(frame.Bounds())
+	}
+	m := BundleManifest{Width: g.Config.Width, Height: g.Config.Height, LoopCount: g.LoopCount}
+	if m.Width == 0 || m.Height == 0 {
+		m.Width, m.Height = bounds.Max.X, bounds.Max.Y
+	}
+	for i, frame := range g.Image {
+		b := frame.Bounds()
+		entry := BundleFrame{
+			File:        fmt.Sprintf("frame_%04d.png", i),
+			Palette:     "palette.json",
+			X:           b.Min.X,
+			Y:           b.Min.Y,
+			Width:       b.Dx(),
+			Height:      b.Dy(),
+			Transparent: transparent(frame.Palette),
+		}
+		if i < len(g.Delay) {
+			entry.Delay = g.Delay[i]
+		}
+		if i < len(g.Disposal) {
+			entry.Disposal = g.Disposal[i]
+		}
+		if !samePalette(frame.Palette, shared) {
+			entry.Palette = fmt.Sprintf("frame_%04d_palette.json", i)
+			if err := writeFile(dir, entry.Palette, func(f *os.File) error {
+				return WriteJSON(f, frame.Palette, entry.Transparent, nil, nil)
+			}); err != nil {
+				return err
+			}
+		}
+		// Store the frame at the origin so the PNG holds only the covered region
+		local := &image.Paletted{Pix: frame.Pix, Stride: frame.Stride, Rect: b.Sub(b.Min), Palette: frame.Palette}
+		if err := writeFile(dir, entry.File, func(f *os.File) error {
+			return png.Encode(f, local)
+		}); err != nil {
+			return err
+		}
+		m.Frames = append(m.Frames, entry)
+	}
+	return writeFile(dir, "manifest.json", func(f *os.File) error {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	})
+}
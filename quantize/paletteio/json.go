@@ -0,0 +1,59 @@
+package paletteio
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// JSONEntry is a single palette entry in a JSON palette document
+type JSONEntry struct {
+	// The non-premultiplied color as #RRGGBBAA
+	Hex string `json:"hex"`
+	R   uint8  `json:"r"`
+	G   uint8  `json:"g"`
+	B   uint8  `json:"b"`
+	A   uint8  `json:"a"`
+	// The number of pixels mapped to the entry, omitted when usage is unknown
+	Pixels *int `json:"pixels,omitempty"`
+}
+
+// JSONPalette is the document written by WriteJSON
+type JSONPalette struct {
+	Colors []JSONEntry `json:"colors"`
+	// The index of the transparent entry, or -1 if there is none
+	Transparent int `json:"transparent"`
+	// The parameters the palette was generated with
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// WriteJSON writes a palette as a JSON document for downstream tools and audits. transparent is the index of the
+// transparent entry, such as the result of quantize.MedianCutQuantizer.TransparentEntry, or -1 if there is none.
+// usage optionally holds the number of pixels mapped to each entry, such as the Pixels of each quantize.EntryStats,
+// and params the generation parameters to record.
+func WriteJSON(w io.Writer, p color.Palette, transparent int, usage []int, params map[string]interface{}) error {
+	if transparent < -1 || transparent >= len(p) {
+		return fmt.Errorf("paletteio: transparent index %d out of range for %d colors", transparent, len(p))
+	}
+	if usage != nil && len(usage) != len(p) {
+		return fmt.Errorf("paletteio: %d usage counts for %d colors", len(usage), len(p))
+	}
+	doc := JSONPalette{Colors: make([]JSONEntry, len(p)), Transparent: transparent, Params: params}
+	for i, c := range p {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		doc.Colors[i] = JSONEntry{
+			Hex: fmt.Sprintf("#%02X%02X%02X%02X", n.R, n.G, n.B, n.A),
+			R:   n.R,
+			G:   n.G,
+			B:   n.B,
+			A:   n.A,
+		}
+		if usage != nil {
+			doc.Colors[i].Pixels = &usage[i]
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
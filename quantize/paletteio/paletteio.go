@@ -0,0 +1,116 @@
+// Package paletteio writes palettes in the file formats used by common art tools, so generated palettes can be
+// loaded alongside the images they were built for
+package paletteio
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// PaintNETMaxColors is the number of colors Paint.NET reads from a palette file; later entries are ignored
+const PaintNETMaxColors = 96
+
+// WritePaintNET writes a palette in Paint.NET's .txt format, with one AARRGGBB hex value per line. Palettes of more
+// than PaintNETMaxColors colors are rejected, since Paint.NET would silently drop the extra entries
+func WritePaintNET(w io.Writer, p color.Palette) error {
+	if len(p) > PaintNETMaxColors {
+		return fmt.Errorf("paletteio: %d colors exceed Paint.NET's limit of %d", len(p), PaintNETMaxColors)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "; paint.net Palette File")
+	fmt.Fprintln(bw, "; Lines that start with a semicolon are comments")
+	fmt.Fprintln(bw, "; Colors are written as 8-digit hexadecimal numbers: aarrggbb")
+	for _, c := range p {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		fmt.Fprintf(bw, "%02X%02X%02X%02X\n", n.A, n.R, n.G, n.B)
+	}
+	return bw.Flush()
+}
+
+// kritaColumns is the number of swatches per row in Krita's palette docker
+const kritaColumns = 16
+
+type kritaColorSet struct {
+	XMLName  xml.Name          `xml:"ColorSet"`
+	Version  string            `xml:"version,attr"`
+	Name     string            `xml:"name,attr"`
+	Comment  string            `xml:"comment,attr"`
+	Columns  int               `xml:"columns,attr"`
+	Rows     int               `xml:"rows,attr"`
+	ReadOnly bool              `xml:"readonly,attr"`
+	Entries  []kritaColorEntry `xml:"ColorSetEntry"`
+}
+
+type kritaColorEntry struct {
+	Name     string `xml:"name,attr"`
+	ID       string `xml:"id,attr"`
+	Spot     bool   `xml:"spot,attr"`
+	BitDepth string `xml:"bitdepth,attr"`
+	RGB      struct {
+		R     float64 `xml:"r,attr"`
+		G     float64 `xml:"g,attr"`
+		B     float64 `xml:"b,attr"`
+		Space string  `xml:"space,attr"`
+	} `xml:"RGB"`
+	Position struct {
+		Row    int `xml:"row,attr"`
+		Column int `xml:"column,attr"`
+	} `xml:"Position"`
+}
+
+// WriteKrita writes a palette as a Krita .kpl color set, a zip archive holding the colors as XML. Alpha is not
+// representable in Krita palettes and is dropped.
+func WriteKrita(w io.Writer, name string, p color.Palette) error {
+	set := kritaColorSet{
+		Version: "1.0",
+		Name:    name,
+		Columns: kritaColumns,
+		Rows:    (len(p) + kritaColumns - 1) / kritaColumns,
+		Entries: make([]kritaColorEntry, len(p)),
+	}
+	for i, c := range p {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		e := &set.Entries[i]
+		e.Name = fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+		e.ID = fmt.Sprint(i)
+		e.BitDepth = "U8"
+		e.RGB.R = float64(n.R) / 255
+		e.RGB.G = float64(n.G) / 255
+		e.RGB.B = float64(n.B) / 255
+		e.RGB.Space = "sRGB-elle-V2-srgbtrc.icc"
+		e.Position.Row = i / kritaColumns
+		e.Position.Column = i % kritaColumns
+	}
+
+	z := zip.NewWriter(w)
+	// The mimetype must be the first entry and stored uncompressed
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "krita/x-colorset"); err != nil {
+		return err
+	}
+	if f, err = z.Create("colorset.xml"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", " ")
+	if err := enc.Encode(set); err != nil {
+		return err
+	}
+	if f, err = z.Create("profiles.xml"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header+"<Profiles/>\n"); err != nil {
+		return err
+	}
+	return z.Close()
+}
@@ -0,0 +1,257 @@
+package paletteio
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testPalette = color.Palette{
+	color.RGBA{255, 0, 0, 255},
+	color.RGBA{0, 128, 255, 255},
+	color.RGBA{0, 0, 0, 0},
+}
+
+func TestWritePaintNET(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePaintNET(&buf, testPalette); err != nil {
+		t.Fatal(err)
+	}
+	var colors []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasPrefix(line, ";") {
+			colors = append(colors, line)
+		}
+	}
+	expected := []string{"FFFF0000", "FF0080FF", "00000000"}
+	if strings.Join(colors, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected colors %v", colors)
+	}
+
+	buf.Reset()
+	if err := WritePaintNET(&buf, make(color.Palette, PaintNETMaxColors+1)); err == nil || buf.Len() != 0 {
+		t.Fatal("Expected a palette over Paint.NET's limit to be rejected before writing")
+	}
+}
+
+func TestWriteKrita(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKrita(&buf, "test", testPalette); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z.File[0].Name != "mimetype" || z.File[0].Method != zip.Store {
+		t.Fatal("Mimetype is not the first stored entry")
+	}
+	var set kritaColorSet
+	for _, f := range z.File {
+		if f.Name != "colorset.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := xml.NewDecoder(r).Decode(&set); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if set.Name != "test" || len(set.Entries) != len(testPalette) {
+		t.Fatalf("Unexpected color set %+v", set)
+	}
+	if set.Entries[1].RGB.B != 1 || set.Entries[1].Position.Column != 1 {
+		t.Fatalf("Unexpected entry %+v", set.Entries[1])
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	params := map[string]interface{}{"colors": 3}
+	if err := WriteJSON(&buf, testPalette, 2, []int{10, 5, 0}, params); err != nil {
+		t.Fatal(err)
+	}
+	var doc JSONPalette
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Colors) != 3 || doc.Colors[1].Hex != "#0080FFFF" || *doc.Colors[0].Pixels != 10 {
+		t.Fatalf("Unexpected colors %+v", doc.Colors)
+	}
+	if doc.Transparent != 2 || doc.Params["colors"] != 3.0 {
+		t.Fatalf("Unexpected metadata %+v", doc)
+	}
+	if err := WriteJSON(&buf, testPalette, 2, []int{1}, nil); err == nil {
+		t.Fatal("Expected an error for mismatched usage counts")
+	}
+	if err := WriteJSON(&buf, testPalette, 3, nil, nil); err == nil {
+		t.Fatal("Expected an error for an out of range transparent index")
+	}
+
+	// A keyed transparent entry needn't have zero alpha
+	buf.Reset()
+	keyed := color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{255, 0, 255, 255}}
+	if err := WriteJSON(&buf, keyed, 1, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil || doc.Transparent != 1 {
+		t.Fatalf("Expected the keyed entry to be reported as transparent, got %d", doc.Transparent)
+	}
+}
+
+func TestWriteFrameBundle(t *testing.T) {
+	local := color.Palette{color.RGBA{1, 2, 3, 255}, color.RGBA{}}
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 8, 8), testPalette),
+			image.NewPaletted(image.Rect(2, 3, 6, 5), local),
+		},
+		Delay:    []int{10, 20},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground},
+		Config:   image.Config{Width: 8, Height: 8},
+	}
+	g.Image[1].Pix[0] = 1
+	dir := t.TempDir()
+	if err := WriteFrameBundle(dir, g, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m BundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Frames) != 2 || m.Frames[0].Palette != "palette.json" || m.Frames[0].Transparent != 2 {
+		t.Fatalf("Unexpected first frame %+v", m.Frames)
+	}
+	second := m.Frames[1]
+	if second.Palette == "palette.json" || second.X != 2 || second.Width != 4 || second.Delay != 20 || second.Transparent != 1 {
+		t.Fatalf("Unexpected second frame %+v", second)
+	}
+	f, err := os.Open(filepath.Join(dir, second.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	frame, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pm, ok := frame.(*image.Paletted); !ok || pm.Bounds().Dx() != 4 || pm.ColorIndexAt(0, 0) != 1 {
+		t.Fatalf("Expected an indexed 4 pixel wide frame, got %T %v", frame, frame.Bounds())
+	}
+}
+
+func TestWriteFrameBundleKeyed(t *testing.T) {
+	magenta := color.RGBA{255, 0, 255, 255}
+	g := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.RGBA{1, 2, 3, 255}, magenta})},
+		Delay: []int{10},
+	}
+	keyed := func(p color.Palette) int {
+		for i := len(p) - 1; i >= 0; i-- {
+			if p[i] == magenta {
+				return i
+			}
+		}
+		return -1
+	}
+	dir := t.TempDir()
+	if err := WriteFrameBundle(dir, g, keyed); err != nil {
+		t.Fatal(err)
+	}
+	var m BundleManifest
+	var doc JSONPalette
+	for name, v := range map[string]interface{}{"manifest.json": &m, "palette.json": &doc} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.Frames[0].Transparent != 1 || doc.Transparent != 1 {
+		t.Fatalf("Expected the keyed entry to be transparent, got %d in the manifest and %d in the palette", m.Frames[0].Transparent, doc.Transparent)
+	}
+}
+
+func TestWriteAPNG(t *testing.T) {
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 6, 6), testPalette),
+			image.NewPaletted(image.Rect(1, 2, 4, 5), testPalette),
+			image.NewPaletted(image.Rect(0, 0, 6, 6), testPalette),
+		},
+		Delay:     []int{10, 20, 30},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+		LoopCount: 2,
+		Config:    image.Config{Width: 8, Height: 6},
+	}
+	g.Image[0].Pix[1] = 1
+	var buf bytes.Buffer
+	if err := WriteAPNG(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoders without APNG support show the first frame, padded to the canvas with the transparent entry
+	m, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, ok := m.(*image.Paletted)
+	if !ok || pm.Bounds() != image.Rect(0, 0, 8, 6) || pm.ColorIndexAt(1, 0) != 1 || pm.ColorIndexAt(7, 0) != 2 {
+		t.Fatalf("Expected the padded first frame, got %T %v", m, m.Bounds())
+	}
+
+	var types []string
+	var fctl [][]byte
+	data := buf.Bytes()[len(pngSignature):]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data[:4])
+		typ := string(data[4:8])
+		if crc32.ChecksumIEEE(data[4:8+n]) != binary.BigEndian.Uint32(data[8+n:]) {
+			t.Fatalf("Chunk %s has a bad checksum", typ)
+		}
+		types = append(types, typ)
+		if typ == "acTL" && (binary.BigEndian.Uint32(data[8:]) != 3 || binary.BigEndian.Uint32(data[12:]) != 3) {
+			t.Fatalf("Expected 3 frames played 3 times, got %v", data[8:16])
+		}
+		if typ == "fcTL" {
+			fctl = append(fctl, data[8:8+n])
+		}
+		data = data[12+n:]
+	}
+	expected := "IHDR acTL PLTE tRNS fcTL IDAT fcTL fdAT fcTL fdAT IEND"
+	if got := strings.Join(types, " "); got != expected {
+		t.Fatalf("Expected chunks %s, got %s", expected, got)
+	}
+	second := fctl[1]
+	if binary.BigEndian.Uint32(second[0:]) != 1 || binary.BigEndian.Uint32(second[4:]) != 3 ||
+		binary.BigEndian.Uint32(second[12:]) != 1 || binary.BigEndian.Uint16(second[20:]) != 20 || second[24] != 1 {
+		t.Fatalf("Unexpected second frame control %v", second)
+	}
+	if seq := binary.BigEndian.Uint32(fctl[2]); seq != 3 {
+		t.Fatalf("Expected the third frame control to be chunk 3 in sequence, got %d", seq)
+	}
+
+	g.Image[1].Palette = color.Palette{color.Black}
+	if err := WriteAPNG(&bytes.Buffer{}, g); err == nil {
+		t.Fatal("Expected an error for frames with differing palettes")
+	}
+}
@@ -0,0 +1,140 @@
+// Package palettes provides standard fixed palettes for terminal, web and retro hardware targets, to be used with
+// quantize.FixedQuantizer or as seeds for generated palettes
+package palettes
+
+import (
+	"image/color"
+	"image/color/palette"
+)
+
+// hexPalette builds an opaque palette from 0xRRGGBB values
+func hexPalette(values ...uint32) color.Palette {
+	p := make(color.Palette, len(values))
+	for i, v := range values {
+		p[i] = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+	}
+	return p
+}
+
+// WebSafe is the 216 color web-safe palette, a 6×6×6 grid with levels at multiples of 0x33
+var WebSafe = append(color.Palette(nil), palette.WebSafe...)
+
+// Xterm256 is the 256 color xterm palette: the 16 system colors, a 6×6×6 cube and a 24 step gray ramp
+var Xterm256 = xterm256()
+
+func xterm256() color.Palette {
+	p := hexPalette(
+		0x000000, 0x800000, 0x008000, 0x808000, 0x000080, 0x800080, 0x008080, 0xc0c0c0,
+		0x808080, 0xff0000, 0x00ff00, 0xffff00, 0x0000ff, 0xff00ff, 0x00ffff, 0xffffff,
+	)
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				p = append(p, color.RGBA{r, g, b, 255})
+			}
+		}
+	}
+	for i := 0; i < 24; i++ {
+		v := uint8(8 + 10*i)
+		p = append(p, color.RGBA{v, v, v, 255})
+	}
+	return p
+}
+
+// CGA is the 16 color CGA palette, with the dark yellow entry adjusted to brown as on IBM monitors
+var CGA = hexPalette(
+	0x000000, 0x0000aa, 0x00aa00, 0x00aaaa, 0xaa0000, 0xaa00aa, 0xaa5500, 0xaaaaaa,
+	0x555555, 0x5555ff, 0x55ff55, 0x55ffff, 0xff5555, 0xff55ff, 0xffff55, 0xffffff,
+)
+
+// EGA is the full 64 color EGA palette, indexed by the rgbRGB bits of the attribute controller
+var EGA = ega()
+
+func ega() color.Palette {
+	p := make(color.Palette, 64)
+	for i := range p {
+		channel := func(primary, secondary uint) uint8 {
+			return uint8(i>>primary&1)*0xaa + uint8(i>>secondary&1)*0x55
+		}
+		p[i] = color.RGBA{channel(2, 5), channel(1, 4), channel(0, 3), 255}
+	}
+	return p
+}
+
+// VGA is the default 256 color palette of VGA mode 13h: the 16 CGA colors, a 16 step gray ramp, 9 rings of 24 hues at
+// three intensities and three saturations, and 8 unused black entries
+var VGA = vga()
+
+func vga() color.Palette {
+	// Channel values are 6-bit, as programmed into the DAC
+	six := func(v uint8) uint8 {
+		return uint8((uint32(v)*255 + 31) / 63)
+	}
+	p := append(color.Palette(nil), CGA...)
+	for _, v := range [16]uint8{0, 5, 8, 11, 14, 17, 20, 24, 28, 32, 36, 40, 45, 50, 56, 63} {
+		p = append(p, color.RGBA{six(v), six(v), six(v), 255})
+	}
+	rings := [9][5]uint8{
+		{0, 16, 31, 47, 63}, {31, 39, 47, 55, 63}, {45, 49, 54, 58, 63},
+		{0, 7, 14, 21, 28}, {14, 17, 21, 24, 28}, {20, 22, 24, 26, 28},
+		{0, 4, 8, 12, 16}, {8, 10, 12, 14, 16}, {11, 12, 13, 15, 16},
+	}
+	for _, v := range rings {
+		// Each ring runs blue, magenta, red, yellow, green, cyan and back towards blue
+		for i := 0; i < 24; i++ {
+			var r, g, b uint8
+			step := i % 4
+			switch i / 4 {
+			case 0:
+				r, g, b = v[step], v[0], v[4]
+			case 1:
+				r, g, b = v[4], v[0], v[4-step]
+			case 2:
+				r, g, b = v[4], v[step], v[0]
+			case 3:
+				r, g, b = v[4-step], v[4], v[0]
+			case 4:
+				r, g, b = v[0], v[4], v[step]
+			case 5:
+				r, g, b = v[0], v[4-step], v[4]
+			}
+			p = append(p, color.RGBA{six(r), six(g), six(b), 255})
+		}
+	}
+	for len(p) < 256 {
+		p = append(p, color.RGBA{0, 0, 0, 255})
+	}
+	return p
+}
+
+// GameBoy is the four shade green palette of the original Game Boy, from darkest to lightest
+var GameBoy = hexPalette(0x0f380f, 0x306230, 0x8bac0f, 0x9bbc0f)
+
+// NES is the 64 entry palette of the NES picture processing unit as commonly emulated, including its repeated blacks
+var NES = hexPalette(
+	0x7c7c7c, 0x0000fc, 0x0000bc, 0x4428bc, 0x940084, 0xa80020, 0xa81000, 0x881400,
+	0x503000, 0x007800, 0x006800, 0x005800, 0x004058, 0x000000, 0x000000, 0x000000,
+	0xbcbcbc, 0x0078f8, 0x0058f8, 0x6844fc, 0xd800cc, 0xe40058, 0xf83800, 0xe45c10,
+	0xac7c00, 0x00b800, 0x00a800, 0x00a844, 0x008888, 0x000000, 0x000000, 0x000000,
+	0xf8f8f8, 0x3cbcfc, 0x6888fc, 0x9878f8, 0xf878f8, 0xf85898, 0xf87858, 0xfca044,
+	0xf8b800, 0xb8f818, 0x58d854, 0x58f898, 0x00e8d8, 0x787878, 0x000000, 0x000000,
+	0xfcfcfc, 0xa4e4fc, 0xb8b8f8, 0xd8b8f8, 0xf8b8f8, 0xf8a4c0, 0xf0d0b0, 0xfce0a8,
+	0xf8d878, 0xd8f878, 0xb8f8b8, 0xb8f8d8, 0x00fcfc, 0xf8d8f8, 0x000000, 0x000000,
+)
+
+// PICO8 is the 16 color palette of the PICO-8 fantasy console
+var PICO8 = hexPalette(
+	0x000000, 0x1d2b53, 0x7e2553, 0x008751, 0xab5236, 0x5f574f, 0xc2c3c7, 0xfff1e8,
+	0xff004d, 0xffa300, 0xffec27, 0x00e436, 0x29adff, 0x83769c, 0xff77a8, 0xffccaa,
+)
+
+// EInk7 is the nominal palette of 7 color ACeP e-paper panels. Real panels render noticeably duller, so measured
+// colors may remap better
+var EInk7 = hexPalette(0x000000, 0xffffff, 0x00ff00, 0x0000ff, 0xff0000, 0xffff00, 0xff8000)
+
+// EInk6 is the nominal palette of 6 color Spectra e-paper panels
+var EInk6 = hexPalette(0x000000, 0xffffff, 0xffff00, 0xff0000, 0x0000ff, 0x00ff00)
+
+// EInk3 is the palette of black, white and red e-paper panels
+var EInk3 = hexPalette(0x000000, 0xffffff, 0xff0000)
@@ -0,0 +1,66 @@
+package palettes
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPaletteSizes(t *testing.T) {
+	for name, tc := range map[string]struct {
+		p    color.Palette
+		size int
+	}{
+		"WebSafe":  {WebSafe, 216},
+		"Xterm256": {Xterm256, 256},
+		"CGA":      {CGA, 16},
+		"EGA":      {EGA, 64},
+		"VGA":      {VGA, 256},
+		"GameBoy":  {GameBoy, 4},
+		"NES":      {NES, 64},
+		"PICO8":    {PICO8, 16},
+		"EInk7":    {EInk7, 7},
+		"EInk6":    {EInk6, 6},
+		"EInk3":    {EInk3, 3},
+	} {
+		if len(tc.p) != tc.size {
+			t.Fatalf("Expected %s to have %d colors, got %d", name, tc.size, len(tc.p))
+		}
+		for _, c := range tc.p {
+			if _, _, _, a := c.RGBA(); a != 0xffff {
+				t.Fatalf("Expected %s to be opaque, got %v", name, c)
+			}
+		}
+	}
+}
+
+func TestPaletteEntries(t *testing.T) {
+	for _, tc := range []struct {
+		p     color.Palette
+		index int
+		want  color.RGBA
+	}{
+		{Xterm256, 196, color.RGBA{255, 0, 0, 255}},
+		{Xterm256, 232, color.RGBA{8, 8, 8, 255}},
+		{Xterm256, 255, color.RGBA{238, 238, 238, 255}},
+		{EGA, 20, color.RGBA{0xaa, 0x55, 0x00, 255}},
+		{EGA, 63, color.RGBA{255, 255, 255, 255}},
+		{VGA, 31, color.RGBA{255, 255, 255, 255}},
+		{VGA, 32, color.RGBA{0, 0, 255, 255}},
+		{VGA, 40, color.RGBA{255, 0, 0, 255}},
+		{VGA, 48, color.RGBA{0, 255, 0, 255}},
+		{VGA, 55, color.RGBA{0, 65, 255, 255}},
+		{VGA, 104, color.RGBA{0, 0, 113, 255}},
+		{CGA, 6, color.RGBA{0xaa, 0x55, 0, 255}},
+		{PICO8, 8, color.RGBA{255, 0, 77, 255}},
+	} {
+		if got := color.RGBAModel.Convert(tc.p[tc.index]); got != tc.want {
+			t.Fatalf("Expected %v at %d, got %v", tc.want, tc.index, got)
+		}
+	}
+	// The first 16 EGA attributes of the default mapping match CGA, apart from brown
+	for i, c := range CGA {
+		if i != 6 && color.RGBAModel.Convert(EGA[i&7|(i&8)*7]) != c {
+			t.Fatalf("Expected EGA to contain CGA color %d", i)
+		}
+	}
+}
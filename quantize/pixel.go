@@ -0,0 +1,38 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// PixelReader reads 8-bit premultiplied pixels from an image, using direct access to the pixel buffers of *image.RGBA,
+// *image.NRGBA, *image.RGBA64, *image.NRGBA64, *image.Gray and *image.YCbCr instead of boxing each pixel through
+// color.Color
+type PixelReader struct {
+	m        image.Image
+	rounding Rounding
+}
+
+// NewPixelReader returns a PixelReader for m that reduces channels deeper than 8 bits using rounding
+func NewPixelReader(m image.Image, rounding Rounding) PixelReader {
+	return PixelReader{m, rounding}
+}
+
+// Bounds returns the bounds of the underlying image
+func (r PixelReader) Bounds() image.Rectangle {
+	return r.m.Bounds()
+}
+
+// RGBAAt returns the color of the pixel at (x, y)
+func (r PixelReader) RGBAAt(x int, y int) color.RGBA {
+	var c color.RGBA
+	if r.rounding == Nearest {
+		c = colorAtNearest(r.m, x, y)
+	} else {
+		c = colorAt(r.m, x, y)
+	}
+	if _, ok := r.m.(*image.YCbCr); ok {
+		c.R, c.G, c.B = color.YCbCrToRGB(c.R, c.G, c.B)
+	}
+	return c
+}
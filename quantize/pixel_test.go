@@ -0,0 +1,41 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestPixelReader(t *testing.T) {
+	src := openTestImage(t, "test_image.jpg")
+	b := image.Rect(0, 0, 32, 32)
+	rgba := image.NewRGBA(b)
+	draw.Draw(rgba, b, src, image.Point{}, draw.Src)
+	nrgba := image.NewNRGBA(b)
+	draw.Draw(nrgba, b, src, image.Point{}, draw.Src)
+	for i := range nrgba.Pix {
+		if i%4 == 3 {
+			nrgba.Pix[i] = uint8(i)
+		}
+	}
+	gray := image.NewGray(b)
+	draw.Draw(gray, b, src, image.Point{}, draw.Src)
+	for _, m := range []image.Image{rgba, nrgba, gray, image.NewRGBA64(b), image.NewNRGBA64(b)} {
+		for _, rounding := range []Rounding{Truncate, Nearest} {
+			r := NewPixelReader(m, rounding)
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					r16, g16, b16, a16 := m.At(x, y).RGBA()
+					expected := color.RGBA{uint8(r16 >> 8), uint8(g16 >> 8), uint8(b16 >> 8), uint8(a16 >> 8)}
+					if rounding == Nearest {
+						expected = color.RGBA{round16(r16), round16(g16), round16(b16), round16(a16)}
+					}
+					if c := r.RGBAAt(x, y); c != expected {
+						t.Fatalf("Expected %v at (%d, %d) of %T, got %v", expected, x, y, m, c)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package quantize
+
+import (
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// EncodePNG8 quantizes m to at most numColors colors, draws it with d as Paletted does and writes it to w as an
+// indexed PNG. When none of AddTransparent, AutoTransparent, AlphaThreshold and QuantizeAlpha is set,
+// AutoTransparent is used so that images with transparent pixels get a transparent entry, which the encoder stores
+// in a tRNS chunk.
+func (q MedianCutQuantizer) EncodePNG8(w io.Writer, m image.Image, numColors int, d draw.Drawer) error {
+	if !q.AddTransparent && !q.AutoTransparent && q.AlphaThreshold == 0 && !q.QuantizeAlpha {
+		q.AutoTransparent = true
+	}
+	return png.Encode(w, q.Paletted(m, numColors, d))
+}
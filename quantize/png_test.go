@@ -0,0 +1,62 @@
+package quantize
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestEncodePNG8(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if x >= 4 {
+				m.Set(x, y, color.NRGBA{uint8(x * 16), 0, uint8(y * 16), 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := (MedianCutQuantizer{}).EncodePNG8(&buf, m, 16, nil); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := decoded.(*image.Paletted)
+	if !ok || len(p.Palette) > 16 {
+		t.Fatalf("Expected an indexed image of at most 16 colors, got %T", decoded)
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if _, _, _, a := p.At(x, y).RGBA(); (a == 0) != (x < 4) {
+				t.Fatalf("Expected only the left columns to be transparent, got alpha %d at (%d, %d)", a, x, y)
+			}
+		}
+	}
+}
+
+func TestEncodePNG8Translucent(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			m.Set(x, y, color.NRGBA{255, 0, 0, uint8(x*16 + 8)})
+		}
+	}
+	var buf bytes.Buffer
+	q := MedianCutQuantizer{Aggregation: Mean, QuantizeAlpha: true}
+	if err := q.EncodePNG8(&buf, m, 16, nil); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 16; x++ {
+		if _, _, _, a := decoded.At(x, 0).RGBA(); a == 0 {
+			t.Fatalf("Expected translucent pixels to stay visible with QuantizeAlpha, got alpha 0 at x=%d", x)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !tinygo
+
+package quantize
+
+import "sync"
+
+type bucketPool struct {
+	sync.Pool
+	maxCap int
+	m      sync.Mutex
+}
+
+func (p *bucketPool) getBucket(c int) colorBucket {
+	p.m.Lock()
+	if p.maxCap > c {
+		p.maxCap = p.maxCap * 99 / 100
+	}
+	if p.maxCap < c {
+		p.maxCap = c
+	}
+	maxCap := p.maxCap
+	p.m.Unlock()
+	val := p.Pool.Get()
+	if val == nil || cap(val.(colorBucket)) < c {
+		return make(colorBucket, maxCap)[0:c]
+	}
+	slice := val.(colorBucket)
+	slice = slice[0:c]
+	for i := range slice {
+		slice[i] = colorPriority{}
+	}
+	return slice
+}
+
+var bpool bucketPool
@@ -0,0 +1,16 @@
+//go:build tinygo
+
+package quantize
+
+// bucketPool allocates a fresh bucket for every request, since sync.Pool offers little under TinyGo's simpler
+// garbage collector and its locking is unnecessary on single-threaded wasm targets
+type bucketPool struct{}
+
+func (p *bucketPool) getBucket(c int) colorBucket {
+	return make(colorBucket, c)
+}
+
+// Put discards a bucket, leaving it to the garbage collector
+func (p *bucketPool) Put(x interface{}) {}
+
+var bpool bucketPool
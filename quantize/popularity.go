@@ -0,0 +1,43 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// PopularityQuantizer implements the go draw.Quantizer interface by choosing the most frequent colors of the image.
+// It is crude but very fast, suiting previews and thumbnails where latency matters more than fidelity.
+type PopularityQuantizer struct {
+	// Histogram and transparency options
+	MedianCut MedianCutQuantizer
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q PopularityQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	return q.quantizeBucket(p, bucket)
+}
+
+// quantizeBucket appends the palette for a histogram to p
+func (q PopularityQuantizer) quantizeBucket(p color.Palette, bucket colorBucket) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	sort.Slice(bucket, func(i, j int) bool {
+		if bucket[i].p != bucket[j].p {
+			return bucket[i].p > bucket[j].p
+		}
+		a, b := bucket[i].RGBA, bucket[j].RGBA
+		return uint32(a.R)<<16|uint32(a.G)<<8|uint32(a.B) < uint32(b.R)<<16|uint32(b.G)<<8|uint32(b.B)
+	})
+	if numColors > len(bucket) {
+		numColors = len(bucket)
+	}
+	for _, c := range bucket[:numColors] {
+		p = append(p, c.RGBA)
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,29 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPopularityQuantizer(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 10, 1))
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	// 5 red, 3 green and 2 blue pixels
+	for x := 0; x < 10; x++ {
+		c := colors[0]
+		if x >= 8 {
+			c = colors[2]
+		} else if x >= 5 {
+			c = colors[1]
+		}
+		m.SetRGBA(x, 0, c)
+	}
+	p := PopularityQuantizer{}.Quantize(make(color.Palette, 0, 2), m)
+	if len(p) != 2 || p[0] != colors[0] || p[1] != colors[1] {
+		t.Fatalf("Expected the two most frequent colors, got %v", p)
+	}
+	if p := (PopularityQuantizer{}).Quantize(make(color.Palette, 0, 8), m); len(p) != 3 {
+		t.Fatalf("Expected every distinct color, got %v", p)
+	}
+}
@@ -0,0 +1,58 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// PosterizeQuantizer implements the go draw.Quantizer interface for posterized and duotone-like styles. Luma is
+// quantized to a number of levels chosen by median cut, while chroma is snapped to a fixed set of hues, giving a
+// palette of every level in every hue.
+type PosterizeQuantizer struct {
+	// Histogram and transparency options
+	MedianCut MedianCutQuantizer
+	// The number of luma levels. If zero, the palette capacity divided by the number of hues is used
+	Levels int
+	// The colors whose chroma each level is drawn in. If empty, levels are gray
+	Hues []color.Color
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q PosterizeQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	type chroma struct{ cb, cr uint8 }
+	hues := []chroma{{128, 128}}
+	if len(q.Hues) > 0 {
+		hues = hues[:0]
+		for _, h := range q.Hues {
+			c := color.RGBAModel.Convert(h).(color.RGBA)
+			_, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			hues = append(hues, chroma{cb, cr})
+		}
+	}
+	levels := q.Levels
+	if levels == 0 || levels*len(hues) > numColors {
+		levels = numColors / len(hues)
+	}
+
+	// Bucket on luma alone by flattening chroma, so every split falls along luma
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	for i, c := range bucket {
+		y, _, _ := color.RGBToYCbCr(c.R, c.G, c.B)
+		bucket[i].RGBA = color.RGBA{y, 128, 128, c.A}
+	}
+	mc := q.MedianCut
+	mc.Aggregation, mc.MinChroma, mc.ColorSpace = Mean, 0, RGB
+	for _, level := range mc.palettize(nil, mc.bucketize(bucket, levels)) {
+		y := level.(color.RGBA).R
+		for _, h := range hues {
+			r, g, b := color.YCbCrToRGB(y, h.cb, h.cr)
+			p = append(p, color.RGBA{r, g, b, 255})
+		}
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
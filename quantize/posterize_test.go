@@ -0,0 +1,29 @@
+package quantize
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPosterizeQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	p := PosterizeQuantizer{Levels: 4}.Quantize(make(color.Palette, 0, 256), i)
+	if len(p) != 4 {
+		t.Fatalf("Expected 4 gray levels, got %d", len(p))
+	}
+	for _, c := range p {
+		if rgba := c.(color.RGBA); rgba.R != rgba.G || rgba.G != rgba.B {
+			t.Fatalf("Expected gray levels, got %v", c)
+		}
+	}
+
+	hues := []color.Color{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	q := PosterizeQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}, Hues: hues}
+	p = q.Quantize(make(color.Palette, 0, 9), i)
+	if len(p) != 9 || q.MedianCut.TransparentEntry(p) != 8 {
+		t.Fatalf("Expected 4 levels in 2 hues and a transparent entry, got %v", p)
+	}
+	if r, _, b, _ := p[0].RGBA(); r <= b {
+		t.Fatalf("Expected the first entry in the red hue, got %v", p[0])
+	}
+}
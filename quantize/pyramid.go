@@ -0,0 +1,136 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// PyramidQuantizer implements the go draw.Quantizer interface by building a palette from a downsampled copy of the
+// image and then refining it with k-means against a sparse sample of the full-resolution pixels. This gives
+// close to full quality at a fraction of the cost on large images. Reserved colors and the transparent entry are
+// kept as is, and the palette is not refined when the MedianCut quantizer has QuantizeAlpha set.
+type PyramidQuantizer struct {
+	// The quantizer used on the downsampled image. Its histogram options, such as Weighting and AlphaThreshold, also
+	// filter the full-resolution sample, with Weighting evaluated on each image in turn
+	MedianCut MedianCutQuantizer
+	// The number of times the image is halved before building the palette. If zero, 2 is used
+	Levels int
+	// The spacing between sampled full-resolution pixels along each axis. If zero, 4 is used
+	SampleStep int
+	// When to stop refining against the full-resolution sample
+	Convergence
+}
+
+// downsample halves an image in each dimension by averaging 2x2 blocks of pixels
+func downsample(m image.Image) *image.RGBA {
+	bounds := m.Bounds()
+	w, h := (bounds.Dx()+1)/2, (bounds.Dy()+1)/2
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var r, g, b, a, n uint32
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					sx, sy := bounds.Min.X+2*x+dx, bounds.Min.Y+2*y+dy
+					if sx >= bounds.Max.X || sy >= bounds.Max.Y {
+						continue
+					}
+					c := rgbaAt(m, sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return out
+}
+
+// sampleBucket builds a histogram from every step-th pixel along each axis of an image, applying the same options
+// as buildBucket
+func (q MedianCutQuantizer) sampleBucket(m image.Image, step int) colorBucket {
+	bounds := m.Bounds()
+	size := ((bounds.Dx()+step-1)/step*((bounds.Dy()+step-1)/step) + 1) * 2
+	sparseBucket := q.getBucket(size)
+	_, ycbcr := m.(*image.YCbCr)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			q.accumulate(sparseBucket, m, x, y, ycbcr)
+		}
+	}
+	return q.collect(sparseBucket, ycbcr)
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q PyramidQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	levels, step := q.Levels, q.SampleStep
+	if levels == 0 {
+		levels = 2
+	}
+	if step <= 0 {
+		step = 4
+	}
+	small := m
+	for i := 0; i < levels && small.Bounds().Dx() > 1 && small.Bounds().Dy() > 1; i++ {
+		small = downsample(small)
+	}
+
+	start := len(p)
+	_, addTransparent := q.MedianCut.target(p)
+	_, reserved := q.MedianCut.reserve(p[:start:start])
+	p = q.MedianCut.Quantize(p, small)
+	generated := q.MedianCut.generatedEntries(p, start, reserved, addTransparent)
+	if len(generated) == 0 {
+		return p
+	}
+	sample := q.MedianCut.sampleBucket(m, step)
+	defer q.MedianCut.putBucket(sample)
+	q.MedianCut.refine(p, generated, sample, q.Convergence)
+	return p
+}
+
+// generatedEntries returns the indices of the entries Quantize added after the first start entries of p, skipping
+// the reserved colors it added and the transparent entry. None are returned when QuantizeAlpha is set, as refining
+// them with k-means would discard their alpha
+func (q MedianCutQuantizer) generatedEntries(p color.Palette, start, reserved int, addTransparent bool) []int {
+	if q.QuantizeAlpha {
+		return nil
+	}
+	transparent := -1
+	if addTransparent {
+		transparent = q.TransparentEntry(p)
+	}
+	first := start + reserved
+	if transparent >= 0 && transparent < first {
+		first++
+	}
+	generated := make([]int, 0, len(p)-first)
+	for i := first; i < len(p); i++ {
+		if i != transparent {
+			generated = append(generated, i)
+		}
+	}
+	return generated
+}
+
+// refine moves the entries of p at the given indices with k-means over a histogram. Refined entries are snapped to
+// Precision, and an entry keeps its old color where its refinement would duplicate another entry
+func (q MedianCutQuantizer) refine(p color.Palette, indices []int, colors colorBucket, conv Convergence) {
+	centers := make([]center, 0, len(indices))
+	for _, i := range indices {
+		centers = append(centers, centerOf(color.RGBAModel.Convert(p[i]).(color.RGBA)))
+	}
+	seen := make(map[color.RGBA]bool, len(p))
+	for _, c := range p {
+		seen[color.RGBAModel.Convert(c).(color.RGBA)] = true
+	}
+	for i, c := range kmeans(colors, centers, conv) {
+		if rgba := q.Precision.snap(c.rgba()); !seen[rgba] {
+			seen[rgba] = true
+			p[indices[i]] = rgba
+		}
+	}
+}
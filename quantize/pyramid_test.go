@@ -0,0 +1,86 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPyramidQuantize(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := PyramidQuantizer{}
+	p := q.Quantize(make([]color.Color, 0, 64), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+	full := MedianCutQuantizer{}
+	t.Logf("Pyramid error %f, full resolution error %f",
+		Quality(p, i).MeanError, Quality(full.Quantize(make([]color.Color, 0, 64), i), i).MeanError)
+
+	q = PyramidQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}, Levels: 3, SampleStep: 8}
+	p = q.Quantize(make([]color.Color, 0, 64), i)
+	if _, _, _, a := p[len(p)-1].RGBA(); len(p) != 64 || a != 0 {
+		t.Fatal("Transparent entry was not preserved")
+	}
+}
+
+func TestPyramidTransparency(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 32; x < 64; x++ {
+			c := color.NRGBA{255, 0, 0, 255}
+			if y%8 < 4 {
+				c = color.NRGBA{0, 0, 255, 255}
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	mc := MedianCutQuantizer{Aggregation: Mean, AddTransparent: true, AlphaThreshold: 1, Scratch: &Scratch{}}
+	p := PyramidQuantizer{MedianCut: mc, Levels: 1, SampleStep: 1}.Quantize(make([]color.Color, 0, 3), m)
+	if len(p) != 3 {
+		t.Fatalf("Expected 3 colors, got %d", len(p))
+	}
+	for _, c := range p[:2] {
+		if rgba := c.(color.RGBA); int(rgba.R)+int(rgba.B) < 250 {
+			t.Fatalf("Expected transparent pixels to stay out of the refined entries, got %v", p)
+		}
+	}
+}
+
+func TestPyramidOptions(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	reserved := color.RGBA{1, 2, 3, 255}
+	p := PyramidQuantizer{MedianCut: MedianCutQuantizer{ReservedColors: []color.Color{reserved}}}.Quantize(make(color.Palette, 0, 16), i)
+	if p.Index(reserved) < 0 || p[p.Index(reserved)] != reserved {
+		t.Fatalf("Expected the reserved color to be kept, got %v", p)
+	}
+
+	mc := MedianCutQuantizer{AddTransparent: true, PlaceTransparent: true}
+	p = PyramidQuantizer{MedianCut: mc}.Quantize(make(color.Palette, 0, 16), i)
+	if len(p) != 16 || mc.TransparentEntry(p) != 0 {
+		t.Fatalf("Expected the transparent entry to stay at index 0, got %v", p)
+	}
+
+	p = PyramidQuantizer{MedianCut: MedianCutQuantizer{Precision: RGB565}}.Quantize(make(color.Palette, 0, 16), i)
+	for _, c := range p {
+		if rgba := c.(color.RGBA); RGB565.snap(rgba) != rgba {
+			t.Fatalf("Expected entries at RGB565 precision, got %v", rgba)
+		}
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{255, uint8(y * 8), 0, uint8(x*8 + 4)})
+		}
+	}
+	p = PyramidQuantizer{MedianCut: MedianCutQuantizer{Aggregation: Mean, QuantizeAlpha: true}}.Quantize(make(color.Palette, 0, 8), m)
+	translucent := false
+	for _, c := range p {
+		_, _, _, a := c.RGBA()
+		translucent = translucent || a > 0 && a < 0xffff
+	}
+	if !translucent {
+		t.Fatalf("Expected translucent entries with QuantizeAlpha, got %v", p)
+	}
+}
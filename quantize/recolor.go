@@ -0,0 +1,78 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// lumaHistogram counts the 8-bit luma values of the pixels of m
+func lumaHistogram(m image.Image) (hist [256]uint64, total uint64) {
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			l, _, _ := color.RGBToYCbCr(c.R, c.G, c.B)
+			hist[l]++
+			total++
+		}
+	}
+	return
+}
+
+// matchLuma returns a table mapping each luma value of m to the value at the same quantile of ref
+func matchLuma(m image.Image, ref image.Image) (table [256]uint8) {
+	src, srcTotal := lumaHistogram(m)
+	dst, dstTotal := lumaHistogram(ref)
+	if srcTotal == 0 || dstTotal == 0 {
+		for i := range table {
+			table[i] = uint8(i)
+		}
+		return
+	}
+	var srcCount, dstCount uint64
+	j := 0
+	for i := range table {
+		// Map to the reference value whose cumulative share first reaches the midpoint of this value's share
+		target := srcCount*dstTotal + src[i]*dstTotal/2
+		for j < 255 && (dstCount+dst[j])*srcTotal <= target {
+			dstCount += dst[j]
+			j++
+		}
+		table[i] = uint8(j)
+		srcCount += src[i]
+	}
+	return
+}
+
+// Recolor maps m onto p, a palette generated for the reference image ref. The luma of each pixel is first matched to
+// the luma histogram of ref, so that sets of images drawn with one palette share a consistent tonal range instead of
+// crowding onto the entries nearest their own colors.
+func Recolor(m image.Image, ref image.Image, p color.Palette) *image.Paletted {
+	table := matchLuma(m, ref)
+	b := m.Bounds()
+	out := image.NewPaletted(b, p)
+	cache := make(map[color.RGBA]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			index, ok := cache[c]
+			if !ok {
+				l, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+				r, g, b := color.YCbCrToRGB(table[l], cb, cr)
+				index = uint8(p.Index(color.RGBA{r, g, b, c.A}))
+				cache[c] = index
+			}
+			out.Pix[out.PixOffset(x, y)] = index
+		}
+	}
+	return out
+}
+
+// TransferPalette builds an n color palette from src and returns dst dithered onto it, so that a set of images
+// can share one color scheme
+func TransferPalette(src, dst image.Image, n int) *image.Paletted {
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, n), src)
+	out := image.NewPaletted(dst.Bounds(), p)
+	FloydSteinberg{}.Draw(out, out.Rect, dst, dst.Bounds().Min)
+	return out
+}
@@ -0,0 +1,52 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func grayRamp(lo, hi uint8) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 1))
+	for x := 0; x < 64; x++ {
+		m.SetRGBA(x, 0, gray(uint8(int(lo)+(int(hi)-int(lo))*x/63)))
+	}
+	return m
+}
+
+func TestRecolor(t *testing.T) {
+	// A dark image recolored with a full-range palette spreads over the whole range
+	ref := grayRamp(0, 255)
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 4), ref)
+	m := grayRamp(0, 63)
+	out := Recolor(m, ref, p)
+	used := make(map[uint8]bool)
+	for _, i := range out.Pix {
+		used[i] = true
+	}
+	if len(used) != len(p) {
+		t.Fatalf("Expected all %d entries to be used, got %d", len(p), len(used))
+	}
+	naive := make(map[int]bool)
+	for x := 0; x < 64; x++ {
+		naive[p.Index(m.At(x, 0))] = true
+	}
+	if len(naive) >= len(used) {
+		t.Fatalf("Expected matching to use more entries than nearest-color mapping, got %d and %d", len(used), len(naive))
+	}
+}
+
+func TestTransferPalette(t *testing.T) {
+	src := openTestImage(t, "test_image.jpg")
+	dst := grayRamp(0, 255)
+	out := TransferPalette(src, dst, 16)
+	if len(out.Palette) != 16 || out.Bounds() != dst.Bounds() {
+		t.Fatalf("Expected a 16 color image with the bounds of dst, got %d colors and %v", len(out.Palette), out.Bounds())
+	}
+	expected := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 16), src)
+	for i, c := range expected {
+		if out.Palette[i] != c {
+			t.Fatalf("Expected the palette of src, got %v", out.Palette)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Recorder wraps a quantizer and drawer so that statistics can be collected from encoders that call both
+// internally, such as gif.Encode. Pass the same *Recorder as the Quantizer and Drawer options, then read Palette
+// and Report once encoding finishes.
+type Recorder struct {
+	// The quantizer generating palettes. If nil, a zero MedianCutQuantizer is used
+	Quantizer draw.Quantizer
+	// The drawer mapping pixels onto the palette. If nil, draw.FloydSteinberg is used
+	Drawer draw.Drawer
+	// The palette most recently returned by Quantize
+	Palette color.Palette
+	// The usage and error of the pixels most recently drawn, measured against the entries actually chosen
+	Report QualityReport
+}
+
+// Quantize generates a palette with the wrapped quantizer and records it
+func (r *Recorder) Quantize(p color.Palette, m image.Image) color.Palette {
+	q := r.Quantizer
+	if q == nil {
+		q = MedianCutQuantizer{}
+	}
+	r.Palette = q.Quantize(p, m)
+	return r.Palette
+}
+
+// Draw draws with the wrapped drawer and, if dst is paletted, records statistics for the drawn pixels
+func (r *Recorder) Draw(dst draw.Image, rect image.Rectangle, src image.Image, sp image.Point) {
+	d := r.Drawer
+	if d == nil {
+		d = draw.FloydSteinberg
+	}
+	d.Draw(dst, rect, src, sp)
+	if pm, ok := dst.(*image.Paletted); ok {
+		rect, sp = clipDraw(dst, rect, src, sp)
+		r.Report = drawnQuality(pm, rect, src, sp)
+	}
+}
+
+// drawnQuality reports the error of the pixels of r in m against the source pixels starting at sp
+func drawnQuality(m *image.Paletted, r image.Rectangle, src image.Image, sp image.Point) QualityReport {
+	report := QualityReport{Entries: make([]EntryStats, len(m.Palette))}
+	entries := make([]color.RGBA, len(m.Palette))
+	for i, c := range m.Palette {
+		entries[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	errs := make([]uint64, len(m.Palette))
+	var total uint64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			index := m.ColorIndexAt(x, y)
+			if int(index) >= len(entries) {
+				continue
+			}
+			d := uint64(sqDiff(rgbaAt(src, sp.X+x-r.Min.X, sp.Y+y-r.Min.Y), entries[index]))
+			report.Entries[index].Pixels++
+			errs[index] += d
+			total += d
+			report.Pixels++
+		}
+	}
+	for i := range report.Entries {
+		if report.Entries[i].Pixels > 0 {
+			report.Entries[i].MeanError = float64(errs[i]) / float64(report.Entries[i].Pixels)
+		}
+	}
+	if report.Pixels > 0 {
+		report.MeanError = float64(total) / float64(report.Pixels)
+	}
+	return report
+}
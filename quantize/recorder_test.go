@@ -0,0 +1,26 @@
+package quantize
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	r := &Recorder{}
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, i, &gif.Options{NumColors: 64, Quantizer: r, Drawer: r}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Palette) != 64 {
+		t.Fatalf("Expected a 64 color palette to be recorded, got %d", len(r.Palette))
+	}
+	b := i.Bounds()
+	if r.Report.Pixels != b.Dx()*b.Dy() || len(r.Report.Entries) != 64 {
+		t.Fatalf("Expected stats for %d pixels and 64 entries, got %d and %d", b.Dx()*b.Dy(), r.Report.Pixels, len(r.Report.Entries))
+	}
+	if r.Report.MeanError <= 0 || r.Report.Worst() < 0 {
+		t.Fatalf("Expected a nonzero error, got %+v", r.Report.MeanError)
+	}
+}
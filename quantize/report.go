@@ -0,0 +1,93 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// EntryStats describes how a single palette entry is used when an image is mapped onto the palette
+type EntryStats struct {
+	// The number of pixels mapped to the entry
+	Pixels int
+	// The mean squared RGB error of the pixels mapped to the entry
+	MeanError float64
+}
+
+// QualityReport summarizes how well a palette represents an image
+type QualityReport struct {
+	// The total number of pixels evaluated
+	Pixels int
+	// The mean squared RGB error over all pixels
+	MeanError float64
+	// Per-entry coverage and error, indexed the same as the palette
+	Entries []EntryStats
+}
+
+// Worst returns the index of the entry contributing the most total error, or -1 if no pixels were mapped
+func (r QualityReport) Worst() int {
+	worst := -1
+	var worstErr float64
+	for i, e := range r.Entries {
+		if total := e.MeanError * float64(e.Pixels); e.Pixels > 0 && (worst < 0 || total > worstErr) {
+			worst, worstErr = i, total
+		}
+	}
+	return worst
+}
+
+// rgbaAt returns the color of a pixel, converting the raw YCbCr values returned by colorAt
+func rgbaAt(m image.Image, x int, y int) color.RGBA {
+	c := colorAt(m, x, y)
+	if _, ok := m.(*image.YCbCr); ok {
+		c.R, c.G, c.B = color.YCbCrToRGB(c.R, c.G, c.B)
+	}
+	return c
+}
+
+// sqDiff returns the squared euclidean distance between two colors in 8-bit RGB space
+func sqDiff(a, b color.RGBA) uint32 {
+	dr := int32(a.R) - int32(b.R)
+	dg := int32(a.G) - int32(b.G)
+	db := int32(a.B) - int32(b.B)
+	return uint32(dr*dr + dg*dg + db*db)
+}
+
+// Quality maps each pixel of m to its nearest entry in p and reports the resulting error
+func Quality(p color.Palette, m image.Image) QualityReport {
+	report := QualityReport{Entries: make([]EntryStats, len(p))}
+	if len(p) == 0 {
+		return report
+	}
+	entries := make([]color.RGBA, len(p))
+	for i, c := range p {
+		entries[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	errs := make([]uint64, len(p))
+	cache := make(map[color.RGBA]int)
+	var total uint64
+	bounds := m.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := rgbaAt(m, x, y)
+			index, ok := cache[c]
+			if !ok {
+				index = p.Index(c)
+				cache[c] = index
+			}
+			d := uint64(sqDiff(c, entries[index]))
+			report.Entries[index].Pixels++
+			errs[index] += d
+			total += d
+			report.Pixels++
+		}
+	}
+	for i := range report.Entries {
+		if report.Entries[i].Pixels > 0 {
+			report.Entries[i].MeanError = float64(errs[i]) / float64(report.Entries[i].Pixels)
+		}
+	}
+	if report.Pixels > 0 {
+		report.MeanError = float64(total) / float64(report.Pixels)
+	}
+	return report
+}
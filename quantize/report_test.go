@@ -0,0 +1,46 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	q := MedianCutQuantizer{}
+	p := q.Quantize(make([]color.Color, 0, 64), i)
+	r := Quality(p, i)
+	t.Logf("Mean error %f over %d pixels", r.MeanError, r.Pixels)
+
+	if len(r.Entries) != len(p) {
+		t.Fatal("Report entries don't match palette")
+	}
+	bounds := i.Bounds()
+	if r.Pixels != bounds.Dx()*bounds.Dy() {
+		t.Fatal("Report didn't cover every pixel")
+	}
+	var pixels int
+	for _, e := range r.Entries {
+		pixels += e.Pixels
+	}
+	if pixels != r.Pixels {
+		t.Fatal("Entry coverage doesn't sum to pixel count")
+	}
+	if w := r.Worst(); w < 0 || w >= len(p) {
+		t.Fatal("Worst entry out of range")
+	}
+}
+
+func TestQualityExact(t *testing.T) {
+	i := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	i.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	i.Set(1, 0, color.RGBA{0, 0, 255, 255})
+	r := Quality(color.Palette{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}, i)
+	if r.MeanError != 0 {
+		t.Fatal("Exact palette reported error")
+	}
+	if r.Entries[0].Pixels != 1 || r.Entries[1].Pixels != 1 {
+		t.Fatal("Pixels mapped to wrong entries")
+	}
+}
@@ -0,0 +1,130 @@
+package quantize
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Riemersma implements the go draw.Drawer interface with Riemersma dithering, which diffuses error along a Hilbert
+// curve through the image. The curve has no preferred direction, so the result is free of the diagonal structure of
+// row-based error diffusion and changes little between similar animation frames. Fully transparent pixels are mapped
+// without dithering.
+// Destinations other than *image.Paletted are drawn without dithering.
+type Riemersma struct {
+	// The number of recent errors carried along the curve. If zero, 16 is used
+	History int
+	// The ratio between the weights of the newest and oldest remembered error. If zero, 16 is used
+	Ratio float64
+}
+
+// hilbert returns the coordinates of the point at distance d along the Hilbert curve filling an n×n square, where n
+// is a power of two
+func hilbert(n int, d int) (x int, y int) {
+	for s := 1; s < n; s *= 2 {
+		rx := 1 & (d / 2)
+		ry := 1 & (d ^ rx)
+		if ry == 0 {
+			if rx == 1 {
+				x, y = s-1-x, s-1-y
+			}
+			x, y = y, x
+		}
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return
+}
+
+// hilbertWalk calls visit with every point of a w×h rectangle in the order of Hilbert curves filling squares laid
+// along its longer axis. Each square's curve ends next to where the following one starts, and the squares are as
+// wide as the shorter side rounded up to a power of two, so elongated rectangles don't walk a square as large as
+// their longer side
+func hilbertWalk(w, h int, visit func(x, y int)) {
+	n := 1
+	for n < w && n < h {
+		n *= 2
+	}
+	wide := w >= h
+	long := w
+	if !wide {
+		long = h
+	}
+	for offset := 0; offset < long; offset += n {
+		for i := 0; i < n*n; i++ {
+			x, y := hilbert(n, i)
+			if wide {
+				x += offset
+			} else {
+				x, y = y, x+offset
+			}
+			if x < w && y < h {
+				visit(x, y)
+			}
+		}
+	}
+}
+
+// Draw dithers the part of src starting at sp onto the rectangle r of dst
+func (d Riemersma) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	p, ok := dst.(*image.Paletted)
+	if !ok || len(p.Palette) == 0 {
+		draw.Draw(dst, r, src, sp, draw.Src)
+		return
+	}
+	r, sp = clipDraw(dst, r, src, sp)
+	if r.Empty() {
+		return
+	}
+	palette := entries16(p.Palette)
+	history := d.History
+	if history <= 0 {
+		history = 16
+	}
+	ratio := d.Ratio
+	if ratio <= 0 {
+		ratio = 16
+	}
+	// Weights grow geometrically from the oldest error to the newest, and are scaled so each error is diffused fully
+	weights := make([]float64, history)
+	var sum float64
+	for i := range weights {
+		weights[i] = 1
+		if history > 1 {
+			weights[i] = math.Pow(ratio, float64(i)/float64(history-1))
+		}
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	errs := make([][4]int32, history) // A ring of the most recent errors, starting at index oldest
+	oldest := 0
+
+	hilbertWalk(r.Dx(), r.Dy(), func(x, y int) {
+		cr, cg, cb, ca := src.At(sp.X+x, sp.Y+y).RGBA()
+		c := [4]int32{int32(cr), int32(cg), int32(cb), int32(ca)}
+		if ca == 0 {
+			// Fully transparent pixels neither receive nor pass on error
+			p.SetColorIndex(r.Min.X+x, r.Min.Y+y, uint8(nearest(palette, c)))
+			return
+		}
+		var acc [4]float64
+		for j, w := range weights {
+			e := errs[(oldest+j)%history]
+			for ch := range acc {
+				acc[ch] += w * float64(e[ch])
+			}
+		}
+		for ch := range c {
+			c[ch] = clamp16(c[ch] + int32(acc[ch]))
+		}
+		index := nearest(palette, c)
+		p.SetColorIndex(r.Min.X+x, r.Min.Y+y, uint8(index))
+		for ch := range c {
+			errs[oldest][ch] = c[ch] - palette[index][ch]
+		}
+		oldest = (oldest + 1) % history
+	})
+}
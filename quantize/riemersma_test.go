@@ -0,0 +1,63 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHilbert(t *testing.T) {
+	const n = 16
+	seen := make(map[image.Point]bool)
+	var last image.Point
+	for d := 0; d < n*n; d++ {
+		x, y := hilbert(n, d)
+		pt := image.Pt(x, y)
+		if !pt.In(image.Rect(0, 0, n, n)) || seen[pt] {
+			t.Fatalf("Point %d at %v is outside the square or repeated", d, pt)
+		}
+		if step := pt.Sub(last); d > 0 && step.X*step.X+step.Y*step.Y != 1 {
+			t.Fatalf("Expected point %d at %v to be adjacent to %v", d, pt, last)
+		}
+		seen[pt], last = true, pt
+	}
+}
+
+func TestHilbertWalk(t *testing.T) {
+	for _, test := range []struct {
+		r image.Rectangle
+		// Whether the sides are multiples of the squares, so no points of the curve are skipped
+		contiguous bool
+	}{
+		{image.Rect(0, 0, 64, 16), true},
+		{image.Rect(0, 0, 8, 40), true},
+		{image.Rect(0, 0, 50, 7), false},
+	} {
+		seen := make(map[image.Point]bool)
+		var last image.Point
+		hilbertWalk(test.r.Dx(), test.r.Dy(), func(x, y int) {
+			pt := image.Pt(x, y)
+			if !pt.In(test.r) || seen[pt] {
+				t.Fatalf("Point %v is outside %v or repeated", pt, test.r)
+			}
+			if step := pt.Sub(last); test.contiguous && len(seen) > 0 && step.X*step.X+step.Y*step.Y != 1 {
+				t.Fatalf("Expected %v to be adjacent to %v in %v", pt, last, test.r)
+			}
+			seen[pt], last = true, pt
+		})
+		if len(seen) != test.r.Dx()*test.r.Dy() {
+			t.Fatalf("Expected every point of %v to be visited, got %d", test.r, len(seen))
+		}
+	}
+}
+
+func TestRiemersma(t *testing.T) {
+	src := image.NewUniform(color.RGBA{64, 64, 64, 255})
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 64, 64), image.Rect(3, 5, 50, 21), image.Rect(0, 0, 4096, 4)} {
+		dst := image.NewPaletted(r, blackWhite)
+		Riemersma{}.Draw(dst, dst.Bounds(), src, image.Point{})
+		if mean := meanGray(dst); mean < 56 || mean > 72 {
+			t.Fatalf("Expected dithered mean near 64 over %v, got %f", r, mean)
+		}
+	}
+}
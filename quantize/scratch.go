@@ -0,0 +1,52 @@
+package quantize
+
+// Scratch holds the buffers used while quantizing, so that callers quantizing many images in a loop can reuse
+// them instead of drawing on the shared pool. Once grown to fit the largest image, a Scratch leaves the palette
+// entries themselves as the only allocations Quantize makes. A Scratch must not be used by more than one
+// quantization at a time.
+type Scratch struct {
+	table colorBucket
+	queue []colorBucket
+}
+
+// getBucket returns a zeroed bucket of length c, growing the scratch table if needed
+func (s *Scratch) getBucket(c int) colorBucket {
+	if cap(s.table) < c {
+		s.table = make(colorBucket, c)
+		return s.table
+	}
+	slice := s.table[:c]
+	for i := range slice {
+		slice[i] = colorPriority{}
+	}
+	return slice
+}
+
+// getBucket returns a zeroed bucket of length c from the quantizer's scratch space, its arena or the shared pool
+func (q MedianCutQuantizer) getBucket(c int) colorBucket {
+	switch {
+	case q.Scratch != nil:
+		return q.Scratch.getBucket(c)
+	case q.Arena != nil:
+		return q.Arena.getBucket(c)
+	}
+	return bpool.getBucket(c)
+}
+
+// putBucket releases a bucket obtained from getBucket
+func (q MedianCutQuantizer) putBucket(b colorBucket) {
+	if q.Scratch == nil && q.Arena == nil {
+		bpool.Put(b)
+	}
+}
+
+// bucketQueue returns an empty queue of buckets with capacity for n
+func (q MedianCutQuantizer) bucketQueue(n int) []colorBucket {
+	if q.Scratch == nil {
+		return make([]colorBucket, 0, n)
+	}
+	if cap(q.Scratch.queue) < n {
+		q.Scratch.queue = make([]colorBucket, 0, n)
+	}
+	return q.Scratch.queue[:0]
+}
@@ -0,0 +1,30 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScratch(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			m.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), uint8(x ^ y), 255})
+		}
+	}
+	q := MedianCutQuantizer{Aggregation: Mean, Scratch: &Scratch{}}
+	p := make(color.Palette, 0, 256)
+	expected := MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 256), m)
+	allocs := testing.AllocsPerRun(10, func() {
+		p = q.Quantize(p[:0], m)
+	})
+	if allocs > float64(len(p)) {
+		t.Fatalf("Expected at most one allocation per palette entry, got %f", allocs)
+	}
+	for i := range expected {
+		if p[i] != expected[i] {
+			t.Fatal("Scratch space changed the palette")
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package quantize
+
+import (
+	"image/color"
+	"sort"
+)
+
+// Ramp marks the palette entries from Start up to but not including End as a cycling ramp, a gradient animated by
+// rotating the entries in place
+type Ramp struct {
+	Start int
+	End   int
+}
+
+// paletteLuma returns the 16-bit luma of a color
+func paletteLuma(c color.Color) uint32 {
+	r, g, b, _ := c.RGBA()
+	return (299*r + 587*g + 114*b) / 1000
+}
+
+// SortPalette returns a copy of p ordered from dark to light. The entries of each ramp are sorted into a gradient
+// and kept contiguous, placed by their mean luma, and the new position of each ramp is returned. Ramps must not
+// overlap.
+func SortPalette(p color.Palette, ramps []Ramp) (color.Palette, []Ramp) {
+	type unit struct {
+		entries color.Palette
+		luma    uint32
+		ramp    int
+	}
+	inRamp := make([]bool, len(p))
+	units := make([]unit, 0, len(p))
+	for i, r := range ramps {
+		entries := append(color.Palette(nil), p[r.Start:r.End]...)
+		sort.SliceStable(entries, func(a, b int) bool {
+			return paletteLuma(entries[a]) < paletteLuma(entries[b])
+		})
+		var sum uint64
+		for j, c := range entries {
+			sum += uint64(paletteLuma(c))
+			inRamp[r.Start+j] = true
+		}
+		var mean uint32
+		if len(entries) > 0 {
+			mean = uint32(sum / uint64(len(entries)))
+		}
+		units = append(units, unit{entries, mean, i})
+	}
+	for i, c := range p {
+		if !inRamp[i] {
+			units = append(units, unit{color.Palette{c}, paletteLuma(c), -1})
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool {
+		return units[a].luma < units[b].luma
+	})
+	sorted := make(color.Palette, 0, len(p))
+	moved := make([]Ramp, len(ramps))
+	for _, u := range units {
+		if u.ramp >= 0 {
+			moved[u.ramp] = Ramp{len(sorted), len(sorted) + len(u.entries)}
+		}
+		sorted = append(sorted, u.entries...)
+	}
+	return sorted, moved
+}
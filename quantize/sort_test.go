@@ -0,0 +1,41 @@
+package quantize
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestSortPalette(t *testing.T) {
+	p := color.Palette{
+		gray(200),
+		color.RGBA{0, 0, 250, 255},
+		color.RGBA{0, 0, 50, 255},
+		color.RGBA{0, 0, 150, 255},
+		gray(10),
+		gray(100),
+	}
+	sorted, ramps := SortPalette(p, []Ramp{{1, 4}})
+	if len(sorted) != len(p) {
+		t.Fatalf("Expected %d entries, got %d", len(p), len(sorted))
+	}
+	r := ramps[0]
+	if r.End-r.Start != 3 {
+		t.Fatalf("Expected a ramp of 3 entries, got %v", r)
+	}
+	for i := r.Start; i < r.End; i++ {
+		if _, _, b, _ := sorted[i].RGBA(); b == 0 {
+			t.Fatalf("Expected ramp entries to stay contiguous, got %v at %d", sorted[i], i)
+		}
+	}
+	for i := 1; i < len(sorted); i++ {
+		inside := i > r.Start && i < r.End
+		outside := (i < r.Start || i > r.End) && (i-1 < r.Start || i-1 >= r.End)
+		if (inside || outside) && paletteLuma(sorted[i-1]) > paletteLuma(sorted[i]) {
+			t.Fatalf("Expected ascending luma at %d, got %v", i, sorted)
+		}
+	}
+}
+
+func gray(v uint8) color.RGBA {
+	return color.RGBA{v, v, v, 255}
+}
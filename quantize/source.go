@@ -0,0 +1,55 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// ColorSource provides typed pixel access to a custom framebuffer, so it can be quantized without boxing every
+// pixel through color.Color
+type ColorSource[T any] interface {
+	// Bounds returns the domain for which At returns pixels
+	Bounds() image.Rectangle
+	// At returns the pixel at (x, y)
+	At(x int, y int) T
+	// RGBA converts a pixel to premultiplied 8-bit RGBA
+	RGBA(c T) color.RGBA
+}
+
+// sourceImage presents a ColorSource as an image.Image for the histogram options that read pixels through one
+type sourceImage[T any] struct {
+	src ColorSource[T]
+}
+
+func (m sourceImage[T]) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (m sourceImage[T]) Bounds() image.Rectangle {
+	return m.src.Bounds()
+}
+
+func (m sourceImage[T]) At(x, y int) color.Color {
+	return m.src.RGBA(m.src.At(x, y))
+}
+
+// Quantize quantizes a typed color source to a palette using q and returns the palette. The histogram options apply
+// as for images, except Rounding, since RGBA already yields 8-bit channels, and Cache. AutoTransparent, Weighting and
+// the options that inspect neighboring pixels, such as OutlierSupport and SaturationBoost, see the source as an
+// image.Image, boxing each pixel they read.
+func Quantize[T any](q MedianCutQuantizer, p color.Palette, src ColorSource[T]) color.Palette {
+	bounds := src.Bounds()
+	m := sourceImage[T]{src}
+	if q.AutoTransparent {
+		q.AddTransparent, q.AlphaThreshold = DetectTransparency(m)
+	}
+	sparseBucket := q.getBucket(bounds.Dx() * bounds.Dy() * 2)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			q.accumulateColor(sparseBucket, m, x, y, src.RGBA(src.At(x, y)), false)
+		}
+	}
+	bucket := q.collect(sparseBucket, false)
+	defer q.putBucket(bucket)
+	return q.quantizeSlice(p, bucket)
+}
@@ -0,0 +1,95 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// rgb565 is a framebuffer of packed 16-bit pixels
+type rgb565 struct {
+	w, h int
+	pix  []uint16
+}
+
+func (f rgb565) Bounds() image.Rectangle { return image.Rect(0, 0, f.w, f.h) }
+
+func (f rgb565) At(x int, y int) uint16 { return f.pix[y*f.w+x] }
+
+func (f rgb565) RGBA(c uint16) color.RGBA {
+	r, g, b := uint8(c>>11), uint8(c>>5&0x3f), uint8(c&0x1f)
+	return color.RGBA{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 255}
+}
+
+// nrgbaSource is a framebuffer of straight alpha pixels
+type nrgbaSource struct {
+	m *image.NRGBA
+}
+
+func (f nrgbaSource) Bounds() image.Rectangle { return f.m.Bounds() }
+
+func (f nrgbaSource) At(x int, y int) color.NRGBA { return f.m.NRGBAAt(x, y) }
+
+func (f nrgbaSource) RGBA(c color.NRGBA) color.RGBA { return color.RGBAModel.Convert(c).(color.RGBA) }
+
+func TestQuantizeSource(t *testing.T) {
+	f := rgb565{16, 16, make([]uint16, 256)}
+	for i := range f.pix {
+		f.pix[i] = uint16(i%4) << 11
+	}
+	p := Quantize[uint16](MedianCutQuantizer{}, make(color.Palette, 0, 8), f)
+	if len(p) != 4 {
+		t.Fatalf("Expected 4 colors, got %d", len(p))
+	}
+	for _, c := range p {
+		if r, g, b, _ := c.RGBA(); r>>8 > 0x19 || g != 0 || b != 0 {
+			t.Fatalf("Unexpected color %v", c)
+		}
+	}
+}
+
+func TestQuantizeSourceOptions(t *testing.T) {
+	f := rgb565{16, 16, make([]uint16, 256)}
+	m := image.NewRGBA(f.Bounds())
+	for i := range f.pix {
+		f.pix[i] = uint16(i*2654435761) ^ uint16(i)
+		m.SetRGBA(i%16, i/16, f.RGBA(f.pix[i]))
+	}
+	for _, q := range []MedianCutQuantizer{
+		{Grayscale: Rec709},
+		{Aggregation: Mean, ToneBias: 1, ChromaBits: 3, Deterministic: true},
+		{OutlierSupport: 1, SaturationBoost: 4},
+		{Weighting: func(m image.Image, x, y int) uint32 { return uint32(x % 3) }},
+	} {
+		got := Quantize[uint16](q, make(color.Palette, 0, 8), f)
+		want := q.Quantize(make(color.Palette, 0, 8), m)
+		if len(got) != len(want) {
+			t.Fatalf("Expected the source to quantize like the equivalent image, got %d and %d colors", len(got), len(want))
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("Expected the source to quantize like the equivalent image, got %v and %v", got, want)
+			}
+		}
+	}
+}
+
+func TestQuantizeSourceAutoTransparent(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 8; x < 16; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(x * 16), 0, 0, 255})
+		}
+	}
+	q := MedianCutQuantizer{AutoTransparent: true}
+	got := Quantize[color.NRGBA](q, make(color.Palette, 0, 4), nrgbaSource{m})
+	want := q.Quantize(make(color.Palette, 0, 4), m)
+	if q.TransparentEntry(got) < 0 || len(got) != len(want) {
+		t.Fatalf("Expected a transparent entry like the equivalent image's, got %v and %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("Expected the source to quantize like the equivalent image, got %v and %v", got, want)
+		}
+	}
+}
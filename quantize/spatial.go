@@ -0,0 +1,268 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// spatialKernel approximates how the eye blurs neighboring pixels, normalized to sum to one
+var spatialKernel = [3][3]float64{
+	{1.0 / 16, 2.0 / 16, 1.0 / 16},
+	{2.0 / 16, 4.0 / 16, 2.0 / 16},
+	{1.0 / 16, 2.0 / 16, 1.0 / 16},
+}
+
+// SpatialQuantizer is an experimental quantizer that chooses the palette and the dithered pixel assignment
+// together, in the spirit of scolorq. It minimizes the difference between the original and quantized images as seen
+// through a small blur, alternating between reassigning pixels and solving for the palette that best fits the
+// assignment. It is slow but gives the best results at very small palette sizes, such as icons and pixel art.
+type SpatialQuantizer struct {
+	// The quantizer choosing the initial palette, along with transparency options
+	MedianCut MedianCutQuantizer
+	// The number of rounds of reassignment and palette fitting. If zero, 3 is used
+	Iterations int
+}
+
+// spatialState holds the original pixels, the assignment and the blurred error of an image being optimized
+type spatialState struct {
+	w, h     int
+	orig     [][3]float64
+	index    []int
+	palette  [][3]float64
+	residual [][3]float64
+}
+
+// neighbors calls fn for each in-bounds pixel within the kernel of (x, y) with its offset into the image and weight
+func (s *spatialState) neighbors(x, y int, fn func(i int, k float64)) {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if nx >= 0 && ny >= 0 && nx < s.w && ny < s.h {
+				fn(ny*s.w+nx, spatialKernel[dy+1][dx+1])
+			}
+		}
+	}
+}
+
+// blurError recomputes the blurred difference between the quantized and original images
+func (s *spatialState) blurError() {
+	for i := range s.residual {
+		s.residual[i] = [3]float64{}
+	}
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			src := y*s.w + x
+			var d [3]float64
+			for ch := range d {
+				d[ch] = s.palette[s.index[src]][ch] - s.orig[src][ch]
+			}
+			s.neighbors(x, y, func(i int, k float64) {
+				for ch := range d {
+					s.residual[i][ch] += k * d[ch]
+				}
+			})
+		}
+	}
+}
+
+// reassign moves each pixel to the entry that most reduces the blurred error, returning the number of changes
+func (s *spatialState) reassign() int {
+	changed := 0
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			i := y*s.w + x
+			cur := s.palette[s.index[i]]
+			best, bestDelta := s.index[i], 0.0
+			for k, entry := range s.palette {
+				if k == s.index[i] {
+					continue
+				}
+				d := [3]float64{entry[0] - cur[0], entry[1] - cur[1], entry[2] - cur[2]}
+				dd := d[0]*d[0] + d[1]*d[1] + d[2]*d[2]
+				var delta float64
+				s.neighbors(x, y, func(n int, w float64) {
+					r := s.residual[n]
+					delta += 2*w*(r[0]*d[0]+r[1]*d[1]+r[2]*d[2]) + w*w*dd
+				})
+				if delta < bestDelta {
+					best, bestDelta = k, delta
+				}
+			}
+			if best != s.index[i] {
+				entry := s.palette[best]
+				d := [3]float64{entry[0] - cur[0], entry[1] - cur[1], entry[2] - cur[2]}
+				s.neighbors(x, y, func(n int, w float64) {
+					for ch := range d {
+						s.residual[n][ch] += w * d[ch]
+					}
+				})
+				s.index[i] = best
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// refit solves the least squares problem for the palette minimizing the blurred error of the current assignment
+func (s *spatialState) refit() {
+	n := len(s.palette)
+	a := make([][]float64, n)
+	for k := range a {
+		a[k] = make([]float64, n)
+	}
+	b := make([][3]float64, n)
+	type weight struct {
+		k int
+		w float64
+	}
+	var ws []weight
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			ws = ws[:0]
+			var blurred [3]float64
+			s.neighbors(x, y, func(i int, k float64) {
+				for ch := range blurred {
+					blurred[ch] += k * s.orig[i][ch]
+				}
+				for j := range ws {
+					if ws[j].k == s.index[i] {
+						ws[j].w += k
+						return
+					}
+				}
+				ws = append(ws, weight{s.index[i], k})
+			})
+			for _, u := range ws {
+				for _, v := range ws {
+					a[u.k][v.k] += u.w * v.w
+				}
+				for ch := range blurred {
+					b[u.k][ch] += u.w * blurred[ch]
+				}
+			}
+		}
+	}
+	// Entries no pixel uses keep their color
+	for k := range a {
+		if a[k][k] == 0 {
+			a[k][k] = 1
+			b[k] = s.palette[k]
+		}
+	}
+	if solved, ok := solve(a, b); ok {
+		for k, c := range solved {
+			for ch := range c {
+				s.palette[k][ch] = math.Max(0, math.Min(255, c[ch]))
+			}
+		}
+	}
+}
+
+// solve solves a·x = b by Gaussian elimination with partial pivoting, reporting false if a is singular
+func solve(a [][]float64, b [][3]float64) ([][3]float64, bool) {
+	n := len(a)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			for ch := range b[row] {
+				b[row][ch] -= f * b[col][ch]
+			}
+		}
+	}
+	x := make([][3]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		x[row] = b[row]
+		for k := row + 1; k < n; k++ {
+			for ch := range x[row] {
+				x[row][ch] -= a[row][k] * x[k][ch]
+			}
+		}
+		for ch := range x[row] {
+			x[row][ch] /= a[row][row]
+		}
+	}
+	return x, true
+}
+
+// Render quantizes m to at most numColors opaque colors and returns the dithered result
+func (q SpatialQuantizer) Render(m image.Image, numColors int) *image.Paletted {
+	mc := q.MedianCut
+	mc.AddTransparent, mc.MaxColors = false, 0
+	initial := mc.Quantize(make(color.Palette, 0, numColors), m)
+	b := m.Bounds()
+	out := image.NewPaletted(b, initial)
+	if len(initial) == 0 {
+		return out
+	}
+	FloydSteinberg{}.Draw(out, b, m, b.Min)
+
+	s := &spatialState{
+		w:        b.Dx(),
+		h:        b.Dy(),
+		orig:     make([][3]float64, b.Dx()*b.Dy()),
+		index:    make([]int, b.Dx()*b.Dy()),
+		palette:  make([][3]float64, len(initial)),
+		residual: make([][3]float64, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			c := rgbaAt(m, b.Min.X+x, b.Min.Y+y)
+			s.orig[y*s.w+x] = [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+			s.index[y*s.w+x] = int(out.Pix[out.PixOffset(b.Min.X+x, b.Min.Y+y)])
+		}
+	}
+	for i, c := range initial {
+		e := c.(color.RGBA)
+		s.palette[i] = [3]float64{float64(e.R), float64(e.G), float64(e.B)}
+	}
+	iterations := q.Iterations
+	if iterations <= 0 {
+		iterations = 3
+	}
+	for iter := 0; iter < iterations; iter++ {
+		s.blurError()
+		if s.reassign() == 0 && iter > 0 {
+			break
+		}
+		s.refit()
+	}
+
+	for i, c := range s.palette {
+		out.Palette[i] = color.RGBA{uint8(math.Round(c[0])), uint8(math.Round(c[1])), uint8(math.Round(c[2])), 255}
+	}
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			out.Pix[out.PixOffset(b.Min.X+x, b.Min.Y+y)] = uint8(s.index[y*s.w+x])
+		}
+	}
+	return out
+}
+
+// Quantize quantizes an image to a palette and returns the palette. Use Render to also obtain the assignment the
+// palette was optimized for, since other drawers won't reproduce it.
+func (q SpatialQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	if numColors > 0 {
+		p = append(p, q.Render(m, numColors).Palette...)
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,53 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// blurredError returns the mean squared difference between two images after blurring both with spatialKernel
+func blurredError(a, b image.Image) float64 {
+	r := a.Bounds()
+	var total float64
+	for y := r.Min.Y + 1; y < r.Max.Y-1; y++ {
+		for x := r.Min.X + 1; x < r.Max.X-1; x++ {
+			var d [3]float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					k := spatialKernel[dy+1][dx+1]
+					ca, cb := rgbaAt(a, x+dx, y+dy), rgbaAt(b, x+dx, y+dy)
+					d[0] += k * (float64(ca.R) - float64(cb.R))
+					d[1] += k * (float64(ca.G) - float64(cb.G))
+					d[2] += k * (float64(ca.B) - float64(cb.B))
+				}
+			}
+			total += d[0]*d[0] + d[1]*d[1] + d[2]*d[2]
+		}
+	}
+	return total / float64((r.Dx()-2)*(r.Dy()-2))
+}
+
+func TestSpatialQuantizer(t *testing.T) {
+	src := openTestImage(t, "test_image.jpg")
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(m, m.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	out := SpatialQuantizer{}.Render(m, 8)
+	if len(out.Palette) != 8 {
+		t.Fatalf("Expected 8 colors, got %d", len(out.Palette))
+	}
+	fs := image.NewPaletted(m.Bounds(), MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 8), m))
+	FloydSteinberg{}.Draw(fs, fs.Rect, m, image.Point{})
+	spatial, dithered := blurredError(m, out), blurredError(m, fs)
+	t.Logf("Blurred error %f spatial, %f median cut with Floyd-Steinberg", spatial, dithered)
+	if spatial >= dithered {
+		t.Fatalf("Expected spatial optimization to lower the blurred error below %f, got %f", dithered, spatial)
+	}
+
+	q := SpatialQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}, Iterations: 1}
+	if p := q.Quantize(make(color.Palette, 0, 5), m); len(p) != 5 || q.MedianCut.TransparentEntry(p) != 4 {
+		t.Fatalf("Expected 4 colors and a transparent entry, got %v", p)
+	}
+}
@@ -0,0 +1,128 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// FrameSource produces the frames of a video or animation one at a time
+type FrameSource interface {
+	// Next returns the next frame, or io.EOF once there are no more frames
+	Next() (image.Image, error)
+}
+
+// FrameSourceFunc adapts an ordinary function to the FrameSource interface
+type FrameSourceFunc func() (image.Image, error)
+
+// Next calls f()
+func (f FrameSourceFunc) Next() (image.Image, error) {
+	return f()
+}
+
+// SliceSource returns a FrameSource producing the frames of a slice in order
+func SliceSource(frames []image.Image) FrameSource {
+	return FrameSourceFunc(func() (image.Image, error) {
+		if len(frames) == 0 {
+			return nil, io.EOF
+		}
+		m := frames[0]
+		frames = frames[1:]
+		return m, nil
+	})
+}
+
+// ChannelSource returns a FrameSource producing the frames received from frames, so a decoder running in another
+// goroutine can hand frames over as they are decoded. The source ends once frames is closed.
+func ChannelSource(frames <-chan image.Image) FrameSource {
+	return FrameSourceFunc(func() (image.Image, error) {
+		m, ok := <-frames
+		if !ok {
+			return nil, io.EOF
+		}
+		return m, nil
+	})
+}
+
+// RawRGBASource returns a FrameSource decoding consecutive raw 8-bit RGBA frames of the given size from r, as
+// produced by "ffmpeg -f rawvideo -pix_fmt rgba". Alpha is straight rather than premultiplied, as ffmpeg writes it,
+// so frames are *image.NRGBA. The same image is reused for every frame, so each frame is only valid until the next
+// call to Next. Errors from r other than io.EOF between frames are returned unchanged.
+func RawRGBASource(r io.Reader, width, height int) FrameSource {
+	frame := image.NewNRGBA(image.Rect(0, 0, width, height))
+	return FrameSourceFunc(func() (image.Image, error) {
+		if _, err := io.ReadFull(r, frame.Pix); err != nil {
+			// ReadFull reports io.EOF only when no bytes were read, so a truncated frame or a failed read is an error
+			return nil, err
+		}
+		return frame, nil
+	})
+}
+
+// compress merges the colors of a histogram into at most n weighted means of nearby colors, reusing its memory
+func (q MedianCutQuantizer) compress(histogram colorBucket, n int) colorBucket {
+	merged := make(colorBucket, 0, n)
+	for _, b := range q.bucketize(histogram, n) {
+		var p, r, g, bl, a uint64
+		for _, c := range b {
+			w := uint64(c.p)
+			p += w
+			r += uint64(c.R) * w
+			g += uint64(c.G) * w
+			bl += uint64(c.B) * w
+			a += uint64(c.A) * w
+		}
+		mean := color.RGBA{uint8((r + p/2) / p), uint8((g + p/2) / p), uint8((bl + p/2) / p), uint8((a + p/2) / p)}
+		if p > math.MaxUint32 {
+			p = math.MaxUint32 // Weights saturate rather than wrap on very long streams
+		}
+		merged = append(merged, colorPriority{uint32(p), mean})
+	}
+	return append(histogram[:0], merged...)
+}
+
+// QuantizeStream quantizes every frame produced by src to a single shared palette, holding only one frame and the
+// combined histogram in memory at a time
+func (q MedianCutQuantizer) QuantizeStream(p color.Palette, src FrameSource) (color.Palette, error) {
+	q.Scratch = nil // Several histograms are held at once
+	histogram, err := q.streamHistogram(src)
+	if histogram != nil {
+		defer q.putBucket(histogram)
+	}
+	if err != nil {
+		return p, err
+	}
+	return q.quantizeSlice(p, histogram), nil
+}
+
+// streamHistogram merges the histograms of every frame produced by src, keeping each to at most MaxBins colors
+func (q MedianCutQuantizer) streamHistogram(src FrameSource) (colorBucket, error) {
+	var histogram colorBucket
+	for {
+		m, err := src.Next()
+		if err == io.EOF {
+			return histogram, nil
+		}
+		if err != nil {
+			return histogram, err
+		}
+		bucket := q.bound(q.buildBucket(m))
+		if histogram == nil {
+			histogram = bucket
+			continue
+		}
+		merged := q.mergeBuckets([]colorBucket{histogram, bucket})
+		q.putBucket(histogram)
+		q.putBucket(bucket)
+		histogram = q.bound(merged)
+	}
+}
+
+// bound compresses a histogram holding more than MaxBins colors, leaving room for the next frame to be merged in
+func (q MedianCutQuantizer) bound(histogram colorBucket) colorBucket {
+	if q.MaxBins > 0 && len(histogram) > q.MaxBins {
+		return q.compress(histogram, q.MaxBins/2+1)
+	}
+	return histogram
+}
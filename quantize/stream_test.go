@@ -0,0 +1,110 @@
+package quantize
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestQuantizeStream(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	a.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	a.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	b := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	b.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	b.Set(1, 0, color.RGBA{0, 0, 255, 255})
+
+	q := MedianCutQuantizer{}
+	p, err := q.QuantizeStream(make([]color.Color, 0, 8), SliceSource([]image.Image{a, b}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 3 {
+		t.Fatalf("Expected 3 shared colors, got %d", len(p))
+	}
+
+	raw := append(append([]byte(nil), a.Pix...), b.Pix...)
+	p, err = q.QuantizeStream(make([]color.Color, 0, 8), RawRGBASource(bytes.NewReader(raw), 2, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 3 {
+		t.Fatalf("Expected 3 shared colors from raw frames, got %d", len(p))
+	}
+
+	frames := make(chan image.Image)
+	go func() {
+		defer close(frames)
+		frames <- a
+		frames <- b
+	}()
+	p, err = q.QuantizeStream(make([]color.Color, 0, 8), ChannelSource(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 3 {
+		t.Fatalf("Expected 3 shared colors from a channel, got %d", len(p))
+	}
+
+	_, err = q.QuantizeStream(make([]color.Color, 0, 8), RawRGBASource(bytes.NewReader(raw[:5]), 2, 1))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatal("Truncated frame was not reported")
+	}
+
+	m, err := RawRGBASource(bytes.NewReader([]byte{200, 100, 0, 128}), 1, 1).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(m.At(0, 0)); c != (color.NRGBA{200, 100, 0, 128}) {
+		t.Fatalf("Expected a translucent pixel to be read with straight alpha, got %v", c)
+	}
+
+	failed := errors.New("broken pipe")
+	_, err = q.QuantizeStream(make([]color.Color, 0, 8), RawRGBASource(iotest.ErrReader(failed), 2, 1))
+	if err != failed {
+		t.Fatalf("Expected the read error to be returned, got %v", err)
+	}
+
+	p, err = q.QuantizeStream(make([]color.Color, 0, 8), SliceSource(nil))
+	if err != nil || len(p) != 0 {
+		t.Fatal("Empty stream produced colors")
+	}
+}
+
+func TestQuantizeStreamMaxBins(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg").(*image.YCbCr)
+	b := i.Bounds()
+	var frames []image.Image
+	for y := b.Min.Y; y+32 <= b.Max.Y; y += 32 {
+		frames = append(frames, i.SubImage(image.Rect(b.Min.X, y, b.Max.X, y+32)))
+	}
+	bounded := MedianCutQuantizer{Aggregation: Mean, MaxBins: 512}
+	histogram := bounded.buildBucket(i)
+	if n := len(bounded.compress(histogram, 100)); n != 100 {
+		t.Fatalf("Expected the histogram to be compressed to 100 colors, got %d", n)
+	}
+
+	single, err := bounded.streamHistogram(SliceSource([]image.Image{i}))
+	if err != nil || len(single) > 512 {
+		t.Fatalf("Expected a single frame's histogram to be bounded to 512 colors, got %d", len(single))
+	}
+
+	p, err := bounded.QuantizeStream(make(color.Palette, 0, 32), SliceSource(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exact, err := MedianCutQuantizer{Aggregation: Mean}.QuantizeStream(make(color.Palette, 0, 32), SliceSource(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 32 {
+		t.Fatalf("Expected 32 colors, got %d", len(p))
+	}
+	if e, got := Quality(exact, i).MeanError, Quality(p, i).MeanError; got > e*1.5 {
+		t.Fatalf("Expected a bounded histogram to cost little quality, got error %f over %f", got, e)
+	}
+}
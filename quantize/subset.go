@@ -0,0 +1,92 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// regionHistogram counts the colors of the pixels of m within r
+func regionHistogram(m image.Image, r image.Rectangle) colorBucket {
+	r = r.Intersect(m.Bounds())
+	counts := make(map[color.RGBA]uint32)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			counts[rgbaAt(m, x, y)]++
+		}
+	}
+	bucket := make(colorBucket, 0, len(counts))
+	for c, n := range counts {
+		bucket = append(bucket, colorPriority{n, c})
+	}
+	return bucket
+}
+
+// SelectSubset chooses the k entries of p that best represent the pixels of m within r, for formats and hardware
+// that assign each region one of several small palette banks. It returns the ascending indices of the chosen entries
+// and the mean squared RGB error of mapping the region onto them.
+func SelectSubset(p color.Palette, m image.Image, r image.Rectangle, k int) ([]int, float64) {
+	if k > len(p) {
+		k = len(p)
+	}
+	if k <= 0 {
+		return nil, 0
+	}
+	entries := make([]color.RGBA, len(p))
+	for i, c := range p {
+		entries[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+	hist := regionHistogram(m, r)
+	var pixels uint64
+	for _, c := range hist {
+		pixels += uint64(c.p)
+	}
+	if pixels == 0 {
+		chosen := make([]int, k)
+		for i := range chosen {
+			chosen[i] = i
+		}
+		return chosen, 0
+	}
+
+	// best holds each color's distance to its nearest chosen entry
+	best := make([]uint32, len(hist))
+	for i := range best {
+		best[i] = ^uint32(0)
+	}
+	cost := func(candidate int, best []uint32) (total uint64) {
+		for i, c := range hist {
+			d := best[i]
+			if cd := sqDiff(c.RGBA, entries[candidate]); cd < d {
+				d = cd
+			}
+			total += uint64(d) * uint64(c.p)
+		}
+		return
+	}
+	chosen := make([]int, 0, k)
+	used := make([]bool, len(p))
+	var total uint64
+	// Greedily add the entry that reduces the error the most
+	for len(chosen) < k {
+		pick, pickCost := -1, uint64(0)
+		for i := range entries {
+			if used[i] {
+				continue
+			}
+			if c := cost(i, best); pick < 0 || c < pickCost {
+				pick, pickCost = i, c
+			}
+		}
+		used[pick] = true
+		chosen = append(chosen, pick)
+		total = pickCost
+		for i, c := range hist {
+			if d := sqDiff(c.RGBA, entries[pick]); d < best[i] {
+				best[i] = d
+			}
+		}
+	}
+	sort.Ints(chosen)
+	return chosen, float64(total) / float64(pixels)
+}
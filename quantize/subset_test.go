@@ -0,0 +1,37 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSelectSubset(t *testing.T) {
+	p := color.Palette{
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+		color.RGBA{250, 250, 250, 255},
+	}
+	m := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 4 {
+				m.Set(x, y, color.RGBA{0, 0, 250, 255})
+			} else {
+				m.Set(x, y, color.RGBA{255, 255, 255, 255})
+			}
+		}
+	}
+	chosen, err := SelectSubset(p, m, m.Bounds(), 2)
+	if len(chosen) != 2 || chosen[0] != 2 || chosen[1] != 3 {
+		t.Fatalf("Expected entries 2 and 3, got %v", chosen)
+	}
+	if err != 50 {
+		t.Fatalf("Expected mean error 50, got %f", err)
+	}
+	// Only the blue half of the image
+	if chosen, err = SelectSubset(p, m, image.Rect(0, 0, 4, 8), 1); len(chosen) != 1 || chosen[0] != 2 || err != 25 {
+		t.Fatalf("Expected entry 2 with error 25, got %v and %f", chosen, err)
+	}
+}
@@ -0,0 +1,97 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// SubtitleFormat identifies a bitmap subtitle format with palette constraints
+type SubtitleFormat uint8
+
+const (
+	// DVD - DVD subpictures, with 4 colors whose alpha is limited to 16 levels
+	DVD SubtitleFormat = iota
+	// PGS - Blu-ray presentation graphics, with 256 colors each carrying its own alpha
+	PGS
+)
+
+// SubtitleEntry is a palette entry as subtitle containers store it, in BT.601 limited-range Y'CbCr with alpha
+type SubtitleEntry struct {
+	Y, Cb, Cr, A uint8
+}
+
+// subtitleEntry converts a straight-alpha color to limited-range BT.601 Y'CbCr
+func subtitleEntry(c color.NRGBA) SubtitleEntry {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	y := 16 + 65.481*r + 128.553*g + 24.966*b
+	cb := 128 - 37.797*r - 74.203*g + 112*b
+	cr := 128 + 112*r - 93.786*g - 18.214*b
+	return SubtitleEntry{uint8(math.Round(y)), uint8(math.Round(cb)), uint8(math.Round(cr)), c.A}
+}
+
+// SubtitlePalette builds the palette for a subtitle bitmap in the given format. Entry 0 is fully transparent and
+// the remaining entries carry the mean alpha of the pixels they represent, so antialiased edges keep their
+// translucency. The palette is returned both as straight-alpha colors and in the form the container stores it.
+func SubtitlePalette(m image.Image, format SubtitleFormat) (color.Palette, []SubtitleEntry) {
+	numColors := 256
+	if format == DVD {
+		numColors = 4
+	}
+
+	// Quantize the straight colors of visible pixels, weighted by their opacity
+	bounds := m.Bounds()
+	straight := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			straight.SetNRGBA(x, y, c)
+		}
+	}
+	opaque := image.NewRGBA(bounds)
+	for i := 0; i < len(straight.Pix); i += 4 {
+		copy(opaque.Pix[i:i+3], straight.Pix[i:i+3])
+		opaque.Pix[i+3] = 255
+	}
+	q := MedianCutQuantizer{
+		Aggregation: Mean,
+		Weighting: func(_ image.Image, x int, y int) uint32 {
+			return uint32(straight.NRGBAAt(x, y).A)
+		},
+	}
+	colors := q.Quantize(make(color.Palette, 0, numColors-1), opaque)
+
+	// Give each entry the mean alpha of the visible pixels mapped to it
+	alpha := make([]uint64, len(colors))
+	count := make([]uint64, len(colors))
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := straight.NRGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			i := colors.Index(color.RGBA{c.R, c.G, c.B, 255})
+			alpha[i] += uint64(c.A)
+			count[i]++
+		}
+	}
+
+	p := make(color.Palette, 1, numColors)
+	p[0] = color.NRGBA{}
+	entries := make([]SubtitleEntry, 1, numColors)
+	entries[0] = subtitleEntry(color.NRGBA{})
+	for i, c := range colors {
+		rgba := c.(color.RGBA)
+		a := uint8(255)
+		if count[i] > 0 {
+			a = uint8(alpha[i] / count[i])
+		}
+		if format == DVD {
+			a = uint8((uint16(a) + 8) / 17 * 17) // DVD contrast values have 4 bits
+		}
+		n := color.NRGBA{rgba.R, rgba.G, rgba.B, a}
+		p = append(p, n)
+		entries = append(entries, subtitleEntry(n))
+	}
+	return p, entries
+}
@@ -0,0 +1,50 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSubtitlePalette(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 8, 1))
+	m.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 255})
+	m.SetNRGBA(2, 0, color.NRGBA{255, 255, 255, 255})
+	m.SetNRGBA(3, 0, color.NRGBA{0, 0, 0, 255})
+	m.SetNRGBA(4, 0, color.NRGBA{0, 0, 0, 128})
+	m.SetNRGBA(5, 0, color.NRGBA{255, 255, 0, 255})
+	m.SetNRGBA(6, 0, color.NRGBA{0, 0, 255, 255})
+
+	p, entries := SubtitlePalette(m, DVD)
+	if len(p) != 4 || len(entries) != 4 {
+		t.Fatalf("Expected 4 DVD entries, got %d", len(p))
+	}
+	if _, _, _, a := p[0].RGBA(); a != 0 || entries[0].A != 0 {
+		t.Fatal("First entry is not transparent")
+	}
+	for _, e := range entries {
+		if e.A%17 != 0 {
+			t.Fatalf("DVD alpha %d doesn't fit in 4 bits", e.A)
+		}
+		if e.Y < 16 || e.Y > 235 {
+			t.Fatalf("Luma %d out of limited range", e.Y)
+		}
+	}
+
+	opaque := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		opaque.SetNRGBA(x, 0, color.NRGBA{255, 255, 255, 255})
+	}
+	if _, entries := SubtitlePalette(opaque, DVD); len(entries) != 2 || entries[1].A != 255 {
+		t.Fatalf("Expected an opaque input to keep alpha 255, got %v", entries)
+	}
+
+	p, _ = SubtitlePalette(m, PGS)
+	if len(p) != 5 {
+		t.Fatalf("Expected transparent entry plus 4 colors, got %d", len(p))
+	}
+	white := subtitleEntry(color.NRGBA{255, 255, 255, 255})
+	if white.Y != 235 || white.Cb != 128 || white.Cr != 128 {
+		t.Fatalf("Unexpected white conversion %v", white)
+	}
+}
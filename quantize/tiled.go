@@ -0,0 +1,113 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// TiledImage is an image divided into square tiles, each holding its own palette
+type TiledImage struct {
+	// The bounds of the whole image
+	Rect image.Rectangle
+	// The side of each tile. Tiles on the right and bottom edges may be smaller
+	TileSize int
+	// The tiles in row-major order
+	Tiles []*image.Paletted
+	cols  int
+}
+
+// ColorModel returns the color model of the image
+func (t *TiledImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the bounds of the image
+func (t *TiledImage) Bounds() image.Rectangle {
+	return t.Rect
+}
+
+// Tile returns the tile containing the pixel at (x, y)
+func (t *TiledImage) Tile(x int, y int) *image.Paletted {
+	col := (x - t.Rect.Min.X) / t.TileSize
+	row := (y - t.Rect.Min.Y) / t.TileSize
+	return t.Tiles[row*t.cols+col]
+}
+
+// At returns the color of the pixel at (x, y)
+func (t *TiledImage) At(x int, y int) color.Color {
+	if !image.Pt(x, y).In(t.Rect) {
+		return color.RGBA{}
+	}
+	return t.Tile(x, y).At(x, y)
+}
+
+// TiledQuantizer quantizes very large images with a local palette per tile. A share of every tile's palette is
+// common to all tiles and pixels may be dithered across tile boundaries, so seams between tiles are not visible.
+type TiledQuantizer struct {
+	// The quantizer generating shared and local entries
+	MedianCut MedianCutQuantizer
+	// The side of each tile. If zero, 256 is used
+	TileSize int
+	// The fraction of each tile's entries taken from the palette shared by all tiles
+	Shared float64
+	// Whether to dither with error carried across tile boundaries. Otherwise each pixel maps to its nearest entry
+	Dither bool
+}
+
+// subImage returns the part of m within r
+func subImage(m image.Image, r image.Rectangle) image.Image {
+	if s, ok := m.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, r, m, r.Min, draw.Src)
+	return dst
+}
+
+// Quantize divides m into tiles and quantizes each to a palette of up to numColors entries
+func (q TiledQuantizer) Quantize(m image.Image, numColors int) *TiledImage {
+	size := q.TileSize
+	if size <= 0 {
+		size = 256
+	}
+	bounds := m.Bounds()
+	t := &TiledImage{Rect: bounds, TileSize: size, cols: (bounds.Dx() + size - 1) / size}
+
+	var shared color.Palette
+	local := q.MedianCut
+	local.MaxColors = 0
+	if n := int(q.Shared * float64(numColors)); n > 0 {
+		sq := q.MedianCut
+		sq.MaxColors = 0
+		shared = sq.Quantize(make(color.Palette, 0, n), m)
+		local.AddTransparent = false
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += size {
+		for x := bounds.Min.X; x < bounds.Max.X; x += size {
+			r := image.Rect(x, y, x+size, y+size).Intersect(bounds)
+			p := local.Quantize(append(make(color.Palette, 0, numColors), shared...), subImage(m, r))
+			t.Tiles = append(t.Tiles, image.NewPaletted(r, p))
+		}
+	}
+
+	if !q.Dither {
+		for _, tile := range t.Tiles {
+			draw.Draw(tile, tile.Rect, m, tile.Rect.Min, draw.Src)
+		}
+		return t
+	}
+	palettes := make([][][4]int32, len(t.Tiles))
+	for i, tile := range t.Tiles {
+		palettes[i] = entries16(tile.Palette)
+	}
+	FloydSteinberg{}.diffuse(bounds, m, bounds.Min, func(x, y int, c [4]int32) [4]int32 {
+		i := (y-bounds.Min.Y)/size*t.cols + (x-bounds.Min.X)/size
+		index := nearest(palettes[i], c)
+		t.Tiles[i].SetColorIndex(x, y, uint8(index))
+		return palettes[i][index]
+	})
+	return t
+}
@@ -0,0 +1,47 @@
+package quantize
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestTiledQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	b := i.Bounds()
+	for _, dither := range []bool{false, true} {
+		q := TiledQuantizer{TileSize: 64, Shared: 0.25, Dither: dither}
+		tiled := q.Quantize(i, 32)
+		cols, rows := (b.Dx()+63)/64, (b.Dy()+63)/64
+		if len(tiled.Tiles) != cols*rows {
+			t.Fatalf("Expected %d tiles, got %d", cols*rows, len(tiled.Tiles))
+		}
+		shared := tiled.Tiles[0].Palette[:8]
+		for _, tile := range tiled.Tiles {
+			if len(tile.Palette) > 32 {
+				t.Fatalf("Expected at most 32 colors per tile, got %d", len(tile.Palette))
+			}
+			for j, c := range shared {
+				if tile.Palette[j] != c {
+					t.Fatalf("Expected tiles to share their first 8 entries")
+				}
+			}
+		}
+		if !dither {
+			var total float64
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					total += float64(sqDiff(rgbaAt(i, x, y), color.RGBAModel.Convert(tiled.At(x, y)).(color.RGBA)))
+				}
+			}
+			tiledErr := total / float64(b.Dx()*b.Dy())
+			single := Quality(MedianCutQuantizer{}.Quantize(make(color.Palette, 0, 32), i), i).MeanError
+			if tiledErr >= single {
+				t.Fatalf("Expected local palettes to beat a single palette, got %f and %f", tiledErr, single)
+			}
+		}
+		c := tiled.At(b.Min.X+70, b.Min.Y+70)
+		if c != tiled.Tile(b.Min.X+70, b.Min.Y+70).At(b.Min.X+70, b.Min.Y+70) {
+			t.Fatalf("Expected At to read from the containing tile")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package quantize
+
+import "image"
+
+// DetectTransparency inspects the alpha channel of m, reporting whether it needs a transparent entry and the alpha
+// below which pixels should map to it. Images with only fully transparent and fully opaque pixels use a threshold
+// of 1, while translucent images are split where Otsu's method best separates their alpha values.
+func DetectTransparency(m image.Image) (addTransparent bool, threshold uint8) {
+	if o, ok := m.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return false, 0
+	}
+	var hist [256]uint64
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			hist[colorAt(m, x, y).A]++
+		}
+	}
+	var total uint64
+	binary := true
+	for a, n := range hist {
+		total += n
+		if n > 0 && a != 0 && a != 255 {
+			binary = false
+		}
+	}
+	if hist[255] == total {
+		return false, 0
+	}
+	if binary {
+		return true, 1
+	}
+
+	// Choose the threshold maximizing the between-class variance of alpha
+	var sum float64
+	for a, n := range hist {
+		sum += float64(a) * float64(n)
+	}
+	var best float64
+	var below uint64
+	var sumBelow float64
+	threshold = 1
+	for t := 1; t < 256; t++ {
+		below += hist[t-1]
+		sumBelow += float64(t-1) * float64(hist[t-1])
+		above := total - below
+		if below == 0 || above == 0 {
+			continue
+		}
+		diff := sumBelow/float64(below) - (sum-sumBelow)/float64(above)
+		if v := float64(below) * float64(above) * diff * diff; v > best {
+			best, threshold = v, uint8(t)
+		}
+	}
+	return true, threshold
+}
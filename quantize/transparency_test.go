@@ -0,0 +1,43 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDetectTransparency(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range m.Pix {
+		m.Pix[i] = 255
+	}
+	if add, _ := DetectTransparency(m); add {
+		t.Fatal("Expected an opaque image to need no transparent entry")
+	}
+	m.SetNRGBA(0, 0, color.NRGBA{})
+	if add, threshold := DetectTransparency(m); !add || threshold != 1 {
+		t.Fatalf("Expected binary alpha to use threshold 1, got %v and %d", add, threshold)
+	}
+	for x := 0; x < 16; x++ {
+		m.SetNRGBA(x, 1, color.NRGBA{255, 0, 0, 40})
+		m.SetNRGBA(x, 2, color.NRGBA{255, 0, 0, 220})
+	}
+	if add, threshold := DetectTransparency(m); !add || threshold <= 40 || threshold > 220 {
+		t.Fatalf("Expected a threshold between 40 and 220, got %v and %d", add, threshold)
+	}
+
+	q := MedianCutQuantizer{AutoTransparent: true}
+	p := q.Quantize(make(color.Palette, 0, 8), m)
+	if q.TransparentEntry(p) != len(p)-1 {
+		t.Fatalf("Expected a trailing transparent entry, got %v", p)
+	}
+	// Faint pixels are left out and the rest are counted at their straight color, so no darkened red appears
+	for _, c := range p[:len(p)-1] {
+		if c != (color.RGBA{255, 255, 255, 255}) && c != (color.RGBA{255, 0, 0, 255}) {
+			t.Fatalf("Expected only white and opaque red entries, got %v", p)
+		}
+	}
+	if p := q.Quantize(make(color.Palette, 0, 8), openTestImage(t, "test_image.jpg")); q.TransparentEntry(p) >= 0 {
+		t.Fatal("Expected no transparent entry for an opaque image")
+	}
+}
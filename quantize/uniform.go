@@ -0,0 +1,58 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// UniformQuantizer implements the go draw.Quantizer interface with a fixed grid of evenly spaced levels per channel,
+// independent of the image. The default 6×6×6 grid is the web-safe palette.
+type UniformQuantizer struct {
+	// Transparency and palette size options
+	MedianCut MedianCutQuantizer
+	// The number of red, green and blue levels. Zero counts default to 6. If the grid exceeds the number of colors
+	// requested, the channels with the most levels are reduced until it fits
+	Levels [3]int
+}
+
+// uniformLevels returns n evenly spaced 8-bit values spanning the full range, or the midpoint when n is 1
+func uniformLevels(n int) []uint8 {
+	if n == 1 {
+		return []uint8{128}
+	}
+	levels := make([]uint8, n)
+	for i := range levels {
+		levels[i] = uint8((i*255 + (n-1)/2) / (n - 1))
+	}
+	return levels
+}
+
+// Quantize appends the grid to the palette, ignoring the image
+func (q UniformQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	levels := q.Levels
+	for i := range levels {
+		if levels[i] <= 0 {
+			levels[i] = 6
+		}
+		if levels[i] > 256 {
+			levels[i] = 256
+		}
+	}
+	for numColors > 0 && levels[0]*levels[1]*levels[2] > numColors {
+		largest := 0
+		for i := range levels {
+			if levels[i] > levels[largest] {
+				largest = i
+			}
+		}
+		levels[largest]--
+	}
+	if numColors > 0 {
+		p = gridPalette(p, uniformLevels(levels[0]), uniformLevels(levels[1]), uniformLevels(levels[2]))
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
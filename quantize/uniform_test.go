@@ -0,0 +1,25 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestUniformQuantizer(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	p := UniformQuantizer{}.Quantize(make(color.Palette, 0, 256), m)
+	if len(p) != 216 {
+		t.Fatalf("Expected the 216 color web-safe palette, got %d", len(p))
+	}
+	if p[1] != (color.RGBA{0, 0, 51, 255}) || p[215] != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("Unexpected web-safe entries %v and %v", p[1], p[215])
+	}
+	if p := (UniformQuantizer{Levels: [3]int{6, 7, 6}}).Quantize(make(color.Palette, 0, 256), m); len(p) != 252 {
+		t.Fatalf("Expected a 6x7x6 grid, got %d colors", len(p))
+	}
+	q := UniformQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}}
+	if p := q.Quantize(make(color.Palette, 0, 16), m); len(p) > 16 || q.MedianCut.TransparentEntry(p) != len(p)-1 {
+		t.Fatalf("Expected a reduced grid and a transparent entry within 16 colors, got %d", len(p))
+	}
+}
@@ -0,0 +1,22 @@
+package quantize
+
+import (
+	"image"
+	"math"
+)
+
+// maxDistance is the largest RGB distance between two colors
+var maxDistance = uint32(math.Ceil(math.Sqrt(3 * 255 * 255)))
+
+// DeltaWeighting returns a Weighting function for the frame following prev, giving each pixel a priority of base plus
+// its RGB distance from the same pixel of prev, so palette entries are spent on moving content. Pixels outside the
+// bounds of prev count as changed as much as possible. A base of zero leaves unchanged pixels out of the histogram.
+func DeltaWeighting(prev image.Image, base uint32) func(image.Image, int, int) uint32 {
+	bounds := prev.Bounds()
+	return func(m image.Image, x int, y int) uint32 {
+		if !image.Pt(x, y).In(bounds) {
+			return base + maxDistance
+		}
+		return base + uint32(math.Sqrt(float64(sqDiff(rgbaAt(m, x, y), rgbaAt(prev, x, y))))+0.5)
+	}
+}
@@ -0,0 +1,37 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestDeltaWeighting(t *testing.T) {
+	gray := color.RGBA{128, 128, 128, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	prev := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(prev, prev.Rect, image.NewUniform(gray), image.Point{}, draw.Src)
+	next := image.NewRGBA(image.Rect(0, 0, 16, 20))
+	draw.Draw(next, next.Rect, prev, image.Point{}, draw.Src)
+	draw.Draw(next, image.Rect(2, 2, 4, 4), image.NewUniform(red), image.Point{}, draw.Src)
+
+	w := DeltaWeighting(prev, 1)
+	if p := w(next, 0, 0); p != 1 {
+		t.Fatalf("Expected an unchanged pixel to keep the base priority, got %d", p)
+	}
+	if p := w(next, 2, 2); p != 1+uint32(math.Sqrt(float64(sqDiff(gray, red)))+0.5) {
+		t.Fatalf("Expected a changed pixel to be weighted by its distance, got %d", p)
+	}
+	if p := w(next, 0, 18); p != 1+maxDistance {
+		t.Fatalf("Expected a pixel outside the previous frame to be weighted fully, got %d", p)
+	}
+
+	// Without a base only the moving content and the rows the previous frame lacks are quantized
+	q := MedianCutQuantizer{Weighting: DeltaWeighting(prev, 0)}
+	p := q.Quantize(make(color.Palette, 0, 4), next)
+	if len(p) != 2 || (p[0] != red && p[1] != red) {
+		t.Fatalf("Expected the palette to hold only changed colors, got %v", p)
+	}
+}
@@ -0,0 +1,222 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+)
+
+// wuSide is the number of cells along each axis of Wu's moment tables, holding 5 bits per channel plus a zero border
+const wuSide = 33
+
+// WuQuantizer implements the go draw.Quantizer interface using Xiaolin Wu's variance minimization method, which
+// repeatedly splits the box of colors whose division most reduces the total squared error
+type WuQuantizer struct {
+	// Histogram and transparency options
+	MedianCut MedianCutQuantizer
+}
+
+// wuMoments holds the cumulative moments of a color histogram over boxes anchored at the origin
+type wuMoments struct {
+	wt, mr, mg, mb [wuSide][wuSide][wuSide]int64
+	m2             [wuSide][wuSide][wuSide]float64
+}
+
+// wuBox is a half-open box of cells (r0, r1] × (g0, g1] × (b0, b1]
+type wuBox struct {
+	r0, r1, g0, g1, b0, b1 int
+	vol                    int
+}
+
+// newWuMoments accumulates the moments of a histogram, then integrates them so any box can be summed in constant
+// time
+func newWuMoments(bucket colorBucket) *wuMoments {
+	w := new(wuMoments)
+	for _, c := range bucket {
+		r, g, b := int(c.R>>3)+1, int(c.G>>3)+1, int(c.B>>3)+1
+		p := int64(c.p)
+		w.wt[r][g][b] += p
+		w.mr[r][g][b] += p * int64(c.R)
+		w.mg[r][g][b] += p * int64(c.G)
+		w.mb[r][g][b] += p * int64(c.B)
+		w.m2[r][g][b] += float64(p) * float64(int(c.R)*int(c.R)+int(c.G)*int(c.G)+int(c.B)*int(c.B))
+	}
+	for r := 1; r < wuSide; r++ {
+		var area, areaR, areaG, areaB [wuSide]int64
+		var area2 [wuSide]float64
+		for g := 1; g < wuSide; g++ {
+			var line, lineR, lineG, lineB int64
+			var line2 float64
+			for b := 1; b < wuSide; b++ {
+				line += w.wt[r][g][b]
+				lineR += w.mr[r][g][b]
+				lineG += w.mg[r][g][b]
+				lineB += w.mb[r][g][b]
+				line2 += w.m2[r][g][b]
+				area[b] += line
+				areaR[b] += lineR
+				areaG[b] += lineG
+				areaB[b] += lineB
+				area2[b] += line2
+				w.wt[r][g][b] = w.wt[r-1][g][b] + area[b]
+				w.mr[r][g][b] = w.mr[r-1][g][b] + areaR[b]
+				w.mg[r][g][b] = w.mg[r-1][g][b] + areaG[b]
+				w.mb[r][g][b] = w.mb[r-1][g][b] + areaB[b]
+				w.m2[r][g][b] = w.m2[r-1][g][b] + area2[b]
+			}
+		}
+	}
+	return w
+}
+
+// volume sums an integrated moment table over a box
+func volume[T int64 | float64](box *wuBox, m *[wuSide][wuSide][wuSide]T) T {
+	return m[box.r1][box.g1][box.b1] - m[box.r1][box.g1][box.b0] - m[box.r1][box.g0][box.b1] + m[box.r1][box.g0][box.b0] -
+		m[box.r0][box.g1][box.b1] + m[box.r0][box.g1][box.b0] + m[box.r0][box.g0][box.b1] - m[box.r0][box.g0][box.b0]
+}
+
+// bottom sums the face of a box at its lower bound along an axis, which volume excludes
+func bottom(box *wuBox, axis colorAxis, m *[wuSide][wuSide][wuSide]int64) int64 {
+	switch axis {
+	case red:
+		return -m[box.r0][box.g1][box.b1] + m[box.r0][box.g1][box.b0] + m[box.r0][box.g0][box.b1] - m[box.r0][box.g0][box.b0]
+	case green:
+		return -m[box.r1][box.g0][box.b1] + m[box.r1][box.g0][box.b0] + m[box.r0][box.g0][box.b1] - m[box.r0][box.g0][box.b0]
+	default:
+		return -m[box.r1][box.g1][box.b0] + m[box.r1][box.g0][box.b0] + m[box.r0][box.g1][box.b0] - m[box.r0][box.g0][box.b0]
+	}
+}
+
+// top sums the face of a box at position pos along an axis
+func top(box *wuBox, axis colorAxis, pos int, m *[wuSide][wuSide][wuSide]int64) int64 {
+	switch axis {
+	case red:
+		return m[pos][box.g1][box.b1] - m[pos][box.g1][box.b0] - m[pos][box.g0][box.b1] + m[pos][box.g0][box.b0]
+	case green:
+		return m[box.r1][pos][box.b1] - m[box.r1][pos][box.b0] - m[box.r0][pos][box.b1] + m[box.r0][pos][box.b0]
+	default:
+		return m[box.r1][box.g1][pos] - m[box.r1][box.g0][pos] - m[box.r0][box.g1][pos] + m[box.r0][box.g0][pos]
+	}
+}
+
+// variance returns the weighted variance of the colors in a box
+func (w *wuMoments) variance(box *wuBox) float64 {
+	dr := float64(volume(box, &w.mr))
+	dg := float64(volume(box, &w.mg))
+	db := float64(volume(box, &w.mb))
+	xx := volume(box, &w.m2)
+	wt := float64(volume(box, &w.wt))
+	if wt == 0 {
+		return 0
+	}
+	return xx - (dr*dr+dg*dg+db*db)/wt
+}
+
+// maximize finds the cut along an axis that maximizes the sum of squared means of both halves, returning the cut
+// position and its score, or -1 if no cut leaves both halves non-empty
+func (w *wuMoments) maximize(box *wuBox, axis colorAxis, first, last int, whole [4]int64) (int, float64) {
+	base := [4]int64{
+		bottom(box, axis, &w.mr), bottom(box, axis, &w.mg), bottom(box, axis, &w.mb), bottom(box, axis, &w.wt),
+	}
+	best, cut := 0.0, -1
+	for i := first; i < last; i++ {
+		half := [4]int64{
+			base[0] + top(box, axis, i, &w.mr),
+			base[1] + top(box, axis, i, &w.mg),
+			base[2] + top(box, axis, i, &w.mb),
+			base[3] + top(box, axis, i, &w.wt),
+		}
+		if half[3] == 0 || half[3] == whole[3] {
+			continue
+		}
+		score := float64(half[0]*half[0]+half[1]*half[1]+half[2]*half[2]) / float64(half[3])
+		rest := [3]int64{whole[0] - half[0], whole[1] - half[1], whole[2] - half[2]}
+		score += float64(rest[0]*rest[0]+rest[1]*rest[1]+rest[2]*rest[2]) / float64(whole[3]-half[3])
+		if score > best {
+			best, cut = score, i
+		}
+	}
+	return cut, best
+}
+
+// cut divides a box in two along the axis giving the greatest reduction in variance, reporting whether it could
+func (w *wuMoments) cut(a, b *wuBox) bool {
+	whole := [4]int64{volume(a, &w.mr), volume(a, &w.mg), volume(a, &w.mb), volume(a, &w.wt)}
+	cutR, maxR := w.maximize(a, red, a.r0+1, a.r1, whole)
+	cutG, maxG := w.maximize(a, green, a.g0+1, a.g1, whole)
+	cutB, maxB := w.maximize(a, blue, a.b0+1, a.b1, whole)
+	*b = *a
+	switch {
+	case maxR >= maxG && maxR >= maxB:
+		if cutR < 0 {
+			return false
+		}
+		a.r1, b.r0 = cutR, cutR
+	case maxG >= maxR && maxG >= maxB:
+		a.g1, b.g0 = cutG, cutG
+	default:
+		a.b1, b.b0 = cutB, cutB
+	}
+	a.vol = (a.r1 - a.r0) * (a.g1 - a.g0) * (a.b1 - a.b0)
+	b.vol = (b.r1 - b.r0) * (b.g1 - b.g0) * (b.b1 - b.b0)
+	return true
+}
+
+// Quantize quantizes an image to a palette and returns the palette
+func (q WuQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bucket := q.MedianCut.buildBucket(m)
+	defer q.MedianCut.putBucket(bucket)
+	return q.quantizeBucket(p, bucket)
+}
+
+// quantizeBucket appends the palette for a histogram to p
+func (q WuQuantizer) quantizeBucket(p color.Palette, bucket colorBucket) color.Palette {
+	numColors, addTransparent := q.MedianCut.target(p)
+	if numColors > 0 && len(bucket) > 0 {
+		w := newWuMoments(bucket)
+		boxes := make([]wuBox, 1, numColors)
+		boxes[0] = wuBox{r1: wuSide - 1, g1: wuSide - 1, b1: wuSide - 1}
+		variances := make([]float64, 1, numColors)
+		next := 0
+		for len(boxes) < numColors {
+			var b wuBox
+			if w.cut(&boxes[next], &b) {
+				boxes = append(boxes, b)
+				variances[next] = 0
+				if boxes[next].vol > 1 {
+					variances[next] = w.variance(&boxes[next])
+				}
+				variances = append(variances, 0)
+				if b.vol > 1 {
+					variances[len(variances)-1] = w.variance(&b)
+				}
+			} else {
+				variances[next] = 0
+			}
+			next = 0
+			for i, v := range variances {
+				if v > variances[next] {
+					next = i
+				}
+			}
+			if variances[next] <= 0 {
+				break
+			}
+		}
+		for i := range boxes {
+			wt := volume(&boxes[i], &w.wt)
+			if wt == 0 {
+				continue
+			}
+			p = append(p, color.RGBA{
+				uint8(volume(&boxes[i], &w.mr) / wt),
+				uint8(volume(&boxes[i], &w.mg) / wt),
+				uint8(volume(&boxes[i], &w.mb) / wt),
+				255,
+			})
+		}
+	}
+	if addTransparent {
+		p = q.MedianCut.withTransparent(p)
+	}
+	return p
+}
@@ -0,0 +1,31 @@
+package quantize
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWuQuantizer(t *testing.T) {
+	i := openTestImage(t, "test_image.jpg")
+	p := WuQuantizer{}.Quantize(make(color.Palette, 0, 64), i)
+	if len(p) != 64 {
+		t.Fatalf("Expected 64 colors, got %d", len(p))
+	}
+	wu := Quality(p, i).MeanError
+	mc := Quality(MedianCutQuantizer{Aggregation: Mean}.Quantize(make(color.Palette, 0, 64), i), i).MeanError
+	t.Logf("Wu error %f, median cut error %f", wu, mc)
+	if wu >= mc {
+		t.Fatalf("Expected Wu error %f to be below median cut error %f", wu, mc)
+	}
+
+	// Images with fewer distinct colors than requested produce short palettes
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	m.Set(1, 0, color.RGBA{0, 0, 255, 255})
+	q := WuQuantizer{MedianCut: MedianCutQuantizer{AddTransparent: true}}
+	p = q.Quantize(make(color.Palette, 0, 16), m)
+	if len(p) != 3 || p[0] == p[1] || q.MedianCut.TransparentEntry(p) != 2 {
+		t.Fatalf("Expected 2 colors and a transparent entry, got %v", p)
+	}
+}